@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/containers/image/v5/transports"
+	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
+)
+
+type catOptions struct {
+	global    *globalOptions
+	image     *imageOptions
+	retryOpts *retry.Options
+}
+
+func catCmd(global *globalOptions) *cobra.Command {
+	sharedFlags, sharedOpts := sharedImageFlags()
+	imageFlags, imageOpts := imageFlags(global, sharedOpts, nil, "", "")
+	retryFlags, retryOpts := retryFlags()
+	opts := catOptions{
+		global:    global,
+		image:     imageOpts,
+		retryOpts: retryOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "cat [command options] IMAGE-NAME PATH",
+		Short: i18n.T("Print a file from image IMAGE-NAME"),
+		Long: fmt.Sprintf(i18n.T(`Print the file at PATH in the filesystem of "IMAGE-NAME" to standard output.
+
+Only the layers down to the one containing PATH are downloaded.
+
+"IMAGE-NAME" may be a registry reference, a "transport:details" name, or a path
+to a docker-archive or oci-archive file or an OCI layout directory.
+
+Supported transports:
+%s
+`), strings.Join(transports.ListNames(), ", ")),
+		RunE: commandAction(opts.run),
+		Example: `gopull cat alpine /etc/os-release
+gopull cat redis.tar /usr/local/bin/redis-server > redis-server`,
+		ValidArgsFunction: autocompleteSupportedTransports,
+	}
+	adjustUsage(cmd)
+	flags := cmd.Flags()
+	flags.AddFlagSet(&sharedFlags)
+	flags.AddFlagSet(&imageFlags)
+	flags.AddFlagSet(&retryFlags)
+	return cmd
+}
+
+func (opts *catOptions) run(args []string, stdout io.Writer) (retErr error) {
+	if len(args) != 2 {
+		return errorShouldDisplayUsage{i18n.New("Exactly two arguments expected")}
+	}
+	ctx, cancel := opts.global.commandTimeoutContext()
+	defer cancel()
+
+	src, img, err := openSourceImage(ctx, opts.image, args[0], opts.retryOpts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing image", err)
+		}
+	}()
+
+	return readImageFile(ctx, src, img.LayerInfos(), opts.retryOpts, args[1], func(_ *tar.Header, r io.Reader) error {
+		_, err := io.Copy(stdout, r)
+		return err
+	})
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/types"
+	"github.com/distribution/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"gopull/pkgs/config"
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/image"
+)
+
+// flagEnvironment maps options to the environment variables setting their default values.
+// An option set in the environment is not set from the configuration file.
+var flagEnvironment = map[string][]string{
+	"authfile": {"REGISTRY_AUTH_FILE"},
+}
+
+// unconfigurableFlags lists the options which have no default in the configuration file.
+// The language is chosen before the configuration file is read.
+var unconfigurableFlags = []string{"config", "profile", "lang", "help", "version"}
+
+// proxyEnvironment lists the environment variables choosing an HTTP(S) proxy.
+var proxyEnvironment = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"}
+
+// loadConfig reads the configuration file chosen by --config, or the default one if it exists,
+// and sets the options of cmd which are set neither on the command line nor in the environment
+// to the values in the --profile profile, else to the defaults in the file.
+func (opts *globalOptions) loadConfig(cmd *cobra.Command) error {
+	path := opts.configPath
+	if path == "" {
+		defaultPath, err := config.DefaultPath()
+		if err != nil {
+			logrus.Debugf("Not reading a configuration file: %v", err)
+			return nil
+		}
+		path = defaultPath
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		if opts.configPath == "" && errors.Is(err, fs.ErrNotExist) {
+			if opts.profile != "" {
+				return i18n.Errorf("profile %q is not defined, there is no configuration file %s", opts.profile, path)
+			}
+			return nil
+		}
+		return i18n.Errorf("reading configuration file: %w", err)
+	}
+	opts.config = cfg
+	opts.configured = map[string]bool{}
+
+	if opts.profile != "" {
+		profile, ok := cfg.Profiles[opts.profile]
+		if !ok {
+			return i18n.Errorf("profile %q is not defined in %s", opts.profile, path)
+		}
+		// A profile is chosen on the command line, so its options are handled as if set there.
+		if err := setFlagsFromConfig(cmd, path, "profiles."+opts.profile, profile, nil); err != nil {
+			return err
+		}
+	}
+	return setFlagsFromConfig(cmd, path, "defaults", cfg.Defaults, opts.configured)
+}
+
+// setFlagsFromConfig sets the options of cmd which are not set yet to values, the section of the configuration file
+// at path, and records them in configured if it is not nil.
+// Options which are not known to any command are an error, options of other commands are ignored.
+func setFlagsFromConfig(cmd *cobra.Command, path, section string, values map[string]any, configured map[string]bool) error {
+	known := map[string]bool{}
+	visitAllFlags(cmd.Root(), func(f *pflag.Flag) {
+		if !f.Hidden && !slices.Contains(unconfigurableFlags, f.Name) {
+			known[f.Name] = true
+		}
+	})
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if !known[name] {
+			return i18n.Errorf("%s: unknown option %q in %s", path, name, section)
+		}
+		f := cmd.Flags().Lookup(name)
+		if f == nil || f.Hidden || f.Changed || setInEnvironment(name) {
+			continue
+		}
+		flagValues, err := config.FlagValues(values[name])
+		if err != nil {
+			return i18n.Errorf("%s: %s.%s: %w", path, section, name, err)
+		}
+		for _, value := range flagValues {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				return i18n.Errorf("%s: invalid value %q for option %q: %w", path, value, name, err)
+			}
+		}
+		if configured != nil {
+			configured[name] = true
+		}
+	}
+	return nil
+}
+
+// expandAlias returns the image name, with an @NAME alias of the configuration file expanded.
+func (opts *globalOptions) expandAlias(name string) (string, error) {
+	var aliases map[string]string
+	if opts.config != nil {
+		aliases = opts.config.Aliases
+	}
+	res, err := image.ExpandAlias(name, aliases)
+	if err != nil {
+		return "", invalidReferenceError{err}
+	}
+	return res, nil
+}
+
+// visitAllFlags calls fn for every flag of cmd and its subcommands.
+func visitAllFlags(cmd *cobra.Command, fn func(*pflag.Flag)) {
+	cmd.PersistentFlags().VisitAll(fn)
+	cmd.LocalNonPersistentFlags().VisitAll(fn)
+	for _, c := range cmd.Commands() {
+		visitAllFlags(c, fn)
+	}
+}
+
+// setInEnvironment returns true if the default of the option name is set by an environment variable.
+func setInEnvironment(name string) bool {
+	for _, env := range flagEnvironment[name] {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// registryConfig returns the settings of the registry of ref in the configuration file, if any.
+func (opts *globalOptions) registryConfig(ref types.ImageReference) (config.Registry, bool) {
+	if ref.Transport() != docker.Transport || ref.DockerReference() == nil {
+		return config.Registry{}, false
+	}
+	return opts.config.Registry(reference.Domain(ref.DockerReference()))
+}
+
+// mirrorReference returns ref, an image to read from, or its location on the mirror of its registry
+// set in the configuration file.
+// The image is then only read from the mirror; the trust policy applies to the mirror location.
+func (opts *globalOptions) mirrorReference(ref types.ImageReference) (types.ImageReference, error) {
+	r, ok := opts.registryConfig(ref)
+	if !ok || r.Mirror == "" {
+		return ref, nil
+	}
+	named := ref.DockerReference()
+	mirrored, err := reference.ParseNormalizedNamed(r.Mirror + "/" + reference.Path(named))
+	if err != nil {
+		return nil, i18n.Errorf("invalid mirror %q of %s: %w", r.Mirror, reference.Domain(named), err)
+	}
+	if tagged, ok := named.(reference.NamedTagged); ok {
+		mirrored, err = reference.WithTag(mirrored, tagged.Tag())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		mirrored, err = reference.WithDigest(mirrored, digested.Digest())
+		if err != nil {
+			return nil, err
+		}
+	}
+	logrus.Debugf("Reading %s from mirror %s", named, mirrored)
+	return docker.NewReference(mirrored)
+}
+
+// setByUser returns true if the option flagPrefix+name, whose value is present, was set on the command line
+// or in the environment rather than in the configuration file.
+func (opts *dockerImageOptions) setByUser(name string, present bool) bool {
+	return present && !opts.global.configured[opts.flagPrefix+name]
+}
+
+// newSystemContextFor returns a *types.SystemContext for accessing ref, corresponding to opts and to the settings of
+// the registry of ref in the configuration file; options set on the command line or in the environment take precedence.
+// It is guaranteed to return a fresh instance, so it is safe to make additional updates to it.
+func (opts *imageOptions) newSystemContextFor(ref types.ImageReference) (*types.SystemContext, error) {
+	ctx, err := opts.newSystemContext()
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.global.useProxy(ref); err != nil {
+		return nil, err
+	}
+	r, ok := opts.global.registryConfig(ref)
+	if !ok {
+		return ctx, nil
+	}
+	tlsVerifySet := opts.setByUser("tls-verify", opts.tlsVerify.Present()) || opts.global.tlsVerify.Present() ||
+		(opts.deprecatedTLSVerify != nil && opts.deprecatedTLSVerify.tlsVerify.Present())
+	if r.TLSVerify != nil && !tlsVerifySet {
+		ctx.DockerInsecureSkipTLSVerify = types.NewOptionalBool(!*r.TLSVerify)
+	}
+	if r.CertDir != "" && !opts.setByUser("cert-dir", opts.dockerCertPath != "") {
+		ctx.DockerCertPath = r.CertDir
+	}
+	authFileSet := opts.setByUser("authfile", opts.authFilePath.Present()) ||
+		(opts.shared.authFilePath != "" && !opts.global.configured["authfile"])
+	if r.AuthFile != "" && !authFileSet {
+		ctx.AuthFilePath = r.AuthFile
+	}
+	credentialsSet := opts.setByUser("creds", opts.credsOption.Present()) || opts.setByUser("username", opts.userName.Present()) ||
+		opts.setByUser("registry-token", opts.registryToken.Present()) || opts.setByUser("no-creds", opts.noCreds)
+	if r.Credentials != "" && !credentialsSet {
+		creds, err := r.ReadCredentials()
+		if err != nil {
+			return nil, err
+		}
+		ctx.DockerAuthConfig, err = getDockerAuth(creds)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ctx, nil
+}
+
+// useProxy records that the registry of ref is accessed by the command, and sets the environment to use the proxy of
+// that registry in the configuration file, if any.
+// containers/image only uses the proxy chosen in the environment, for all connections of the process, and net/http
+// reads the environment once, so all the registries a command accesses must have the same proxy in the configuration
+// file. A proxy set in the environment takes precedence and is used for all registries.
+func (opts *globalOptions) useProxy(ref types.ImageReference) error {
+	if ref.Transport() != docker.Transport || ref.DockerReference() == nil {
+		return nil
+	}
+	if opts.proxies == nil {
+		if slices.ContainsFunc(proxyEnvironment, func(env string) bool { return os.Getenv(env) != "" }) {
+			return nil
+		}
+		opts.proxies = map[string]string{}
+	}
+	registry := reference.Domain(ref.DockerReference())
+	r, _ := opts.config.Registry(registry)
+	for other, proxy := range opts.proxies {
+		if proxy != r.Proxy {
+			return i18n.Errorf("registries %s and %s have different proxies in the configuration file, a command can only use one proxy", other, registry)
+		}
+	}
+	if len(opts.proxies) == 0 && r.Proxy != "" {
+		for _, env := range []string{"HTTPS_PROXY", "HTTP_PROXY"} {
+			if err := os.Setenv(env, r.Proxy); err != nil {
+				return err
+			}
+		}
+	}
+	opts.proxies[registry] = r.Proxy
+	return nil
+}
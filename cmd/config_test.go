@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/types"
+	"github.com/distribution/reference"
+
+	"gopull/pkgs/config"
+)
+
+func TestUseProxy(t *testing.T) {
+	cfg := &config.Config{Registries: map[string]config.Registry{
+		"harbor.internal": {Proxy: "http://proxy.internal:3128"},
+		"quay.io":         {Proxy: "http://proxy.internal:3128"},
+		"ghcr.io":         {Proxy: "http://other.internal:3128"},
+		"docker.io":       {TLSVerify: new(bool)},
+	}}
+	for _, c := range []struct {
+		env        string   // HTTPS_PROXY before the command
+		registries []string // Accessed by the command, in order
+		expected   string   // HTTPS_PROXY after the command, or "error"
+	}{
+		{"", []string{"harbor.internal", "quay.io", "harbor.internal"}, "http://proxy.internal:3128"},
+		{"", []string{"docker.io"}, ""},
+		{"", []string{"harbor.internal", "ghcr.io"}, "error"},
+		{"", []string{"harbor.internal", "docker.io"}, "error"},
+		{"", []string{"docker.io", "harbor.internal"}, "error"},
+		{"http://env.internal:3128", []string{"harbor.internal", "ghcr.io"}, "http://env.internal:3128"},
+	} {
+		for _, env := range proxyEnvironment {
+			t.Setenv(env, "")
+		}
+		t.Setenv("HTTPS_PROXY", c.env)
+		global := &globalOptions{config: cfg}
+		var err error
+		for _, registry := range c.registries {
+			named, parseErr := reference.ParseNormalizedNamed(registry + "/app:latest")
+			if parseErr != nil {
+				t.Fatal(parseErr)
+			}
+			var ref types.ImageReference
+			ref, err = docker.NewReference(named)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = global.useProxy(ref); err != nil {
+				break
+			}
+		}
+		if c.expected == "error" {
+			if err == nil {
+				t.Errorf("%v: expected an error", c.registries)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", c.registries, err)
+		} else if proxy := os.Getenv("HTTPS_PROXY"); proxy != c.expected {
+			t.Errorf("%v: HTTPS_PROXY %q, expected %q", c.registries, proxy, c.expected)
+		}
+	}
+}
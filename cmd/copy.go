@@ -1,14 +1,25 @@
 package cmd
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
+	"os"
 
 	commonFlag "github.com/containers/common/pkg/flag"
-	"github.com/containers/common/pkg/retry"
 	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/daemon"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports"
 	"github.com/containers/image/v5/types"
+	"golang.org/x/term"
+	"golang.org/x/time/rate"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/progress"
+	"gopull/pkgs/retry"
+	"gopull/pkgs/throttle"
 )
 
 type copyOptions struct {
@@ -17,38 +28,79 @@ type copyOptions struct {
 	srcImage            *imageOptions
 	destImage           *imageDestOptions
 	retryOpts           *retry.Options
+	verify              *verifyOptions            // Signatures required from the source; nil if not supported by the command
+	sign                *signOptions              // Signing of the destination; nil if not supported by the command
+	rateLimit           *rateLimitOptions         // Bandwidth limits; nil if not supported by the command
+	output              *outputFileOptions        // Writing of archive destinations; nil if not supported by the command
+	encrypt             *encryptionOptions        // Layer encryption of the destination; nil if not supported by the command
+	decrypt             *decryptionOptions        // Layer decryption of the source; nil if not supported by the command
 	format              commonFlag.OptionalString // Force conversion of the image to a specified format
 	quiet               bool                      // Suppress output information when copying images
+	dryRun              bool                      // Only report what would be copied
+	progress            string                    // --progress format, one of the progress* values
+	resolveShortNames   bool                      // Source images are registry names, whose short names are resolved
 }
 
 type buildImageRefer func(string) (types.ImageReference, *types.SystemContext, error)
 
-func (opts *copyOptions) execCopy(args []string, stdout io.Writer, s buildImageRefer, d buildImageRefer) (retErr error) {
+func (opts *copyOptions) execCopy(args []string, stdout io.Writer, s buildImageRefer, d buildImageRefer) error {
 	if len(args) != 1 {
-		return errorShouldDisplayUsage{errors.New("image is required")}
+		return errorShouldDisplayUsage{i18n.New("image is required")}
 	}
 	opts.deprecatedTLSVerify.warnIfUsed([]string{"--src-tls-verify", "--dest-tls-verify"})
-	imageName := args[0]
+	imageName, err := opts.global.expandAlias(args[0])
+	if err != nil {
+		return err
+	}
+	if err := checkProgressFormat(opts.progress); err != nil {
+		return err
+	}
+
+	srcNames := &resolvedName{candidates: []string{imageName}}
+	if opts.resolveShortNames {
+		srcNames, err = opts.global.resolveShortName(imageName)
+		if err != nil {
+			return err
+		}
+	}
+	return srcNames.try(func(srcName string) error {
+		return opts.copyFrom(srcName, stdout, s, d)
+	})
+}
+
+// copyFrom copies srcName, a candidate name of the image argument, to the destination built for srcName.
+// The destination is built for the resolved name, so that e.g. a short name found on another registry
+// is not tagged or named as a Docker Hub image.
+func (opts *copyOptions) copyFrom(srcName string, stdout io.Writer, s buildImageRefer, d buildImageRefer) (retErr error) {
+	srcRef, sourceCtx, err := s(srcName)
+	if err != nil {
+		return err
+	}
 
-	policyContext, err := opts.global.getPolicyContext()
+	verify := opts.verify
+	if verify == nil {
+		verify = &verifyOptions{}
+	}
+	policyContext, err := verify.getPolicyContext(opts.global, srcRef)
 	if err != nil {
-		return fmt.Errorf("error loading trust policy: %v", err)
+		return i18n.Errorf("error loading trust policy: %v", err)
 	}
 	defer func() {
 		if err := policyContext.Destroy(); err != nil {
 			retErr = noteCloseFailure(retErr, "tearing down policy context", err)
 		}
 	}()
-
-	srcRef, sourceCtx, err := s(imageName)
+	cleanup, err := verify.enableSigstoreAttachments(sourceCtx, srcRef)
 	if err != nil {
 		return err
 	}
+	defer cleanup()
 
-	destRef, destCtx, err := d(imageName)
+	destRef, destCtx, err := d(srcName)
 	if err != nil {
 		return err
 	}
+	srcName, destName := transports.ImageName(srcRef), transports.ImageName(destRef)
 
 	var manifestType string
 	if opts.format.Present() {
@@ -60,21 +112,148 @@ func (opts *copyOptions) execCopy(args []string, stdout io.Writer, s buildImageR
 
 	ctx, cancel := opts.global.commandTimeoutContext()
 	defer cancel()
+	destTransport := destRef.Transport().Name()
+	defer func() {
+		if !interrupted(ctx, retErr) {
+			return
+		}
+		cause := context.Cause(ctx)
+		switch {
+		case opts.dryRun:
+			retErr = i18n.Errorf("%w: the copy of %s to %s was not planned, nothing was written", cause, srcName, destName)
+		case opts.output != nil && opts.output.removedTemp:
+			retErr = i18n.Errorf("%w: %s was not copied to %s, the incomplete archive was removed", cause, srcName, destName)
+		case destTransport == docker.Transport.Name():
+			retErr = i18n.Errorf("%w: %s was not copied to %s, blobs already pushed remain in the registry", cause, srcName, destName)
+		case destTransport == daemon.Transport.Name():
+			retErr = i18n.Errorf("%w: %s was not copied to %s, the image is not tagged but layers the docker daemon already loaded remain", cause, srcName, destName)
+		default:
+			retErr = i18n.Errorf("%w: %s was not copied to %s", cause, srcName, destName)
+		}
+	}()
 
-	if opts.quiet {
-		stdout = nil
+	if opts.dryRun {
+		return opts.planCopy(ctx, policyContext, srcRef, sourceCtx, destRef, destCtx, stdout)
+	}
+
+	// Progress is reported on stderr, so that stdout stays clean for data; except for --progress json,
+	// which is the data requested.
+	var emitter *progressEmitter
+	switch {
+	case opts.progress == progressJSON:
+		emitter = newProgressEmitter(func(events <-chan progress.Event) error {
+			return progress.WriteJSON(stdout, events)
+		})
+	case !opts.quiet:
+		emitter = newProgressEmitter(func(events <-chan progress.Event) error {
+			return progress.WriteText(os.Stderr, term.IsTerminal(int(os.Stderr.Fd())), events)
+		})
+	}
+	if emitter != nil {
+		defer func() {
+			if err := emitter.close(); err != nil && retErr == nil {
+				retErr = i18n.Errorf("writing progress: %w", err)
+			}
+		}()
+		// Failures before the copy, e.g. an existing archive or invalid options, are reported too.
+		defer func() {
+			if retErr != nil {
+				emitter.fail(srcName, destName, retErr)
+			}
+		}()
+	}
+
+	restart := func() error { return nil }
+	if opts.output != nil {
+		var done func(error) error
+		destRef, restart, done, err = opts.output.begin(ctx, srcRef, sourceCtx, destRef, opts.retryOpts)
+		if err != nil {
+			return err
+		}
+		if destRef == nil {
+			if !opts.quiet {
+				fmt.Fprintf(os.Stderr, i18n.T("Skipping %s, it already contains the image\n"), destName)
+			}
+			return nil
+		}
+		defer func() {
+			retErr = done(retErr)
+		}()
+	}
+
+	var limiters []*rate.Limiter
+	if opts.rateLimit != nil {
+		limiters, err = opts.rateLimit.copyLimiters(srcRef, destRef)
+		if err != nil {
+			return err
+		}
 	}
 
 	opts.destImage.warnAboutIneffectiveOptions(destRef.Transport())
 
+	copyOpts := &copy.Options{
+		SourceCtx:             sourceCtx,
+		DestinationCtx:        destCtx,
+		ForceManifestMIMEType: manifestType,
+		ImageListSelection:    copy.CopySystemImage,
+	}
+	if opts.encrypt != nil {
+		if err := opts.encrypt.apply(copyOpts); err != nil {
+			return err
+		}
+	}
+	if opts.decrypt != nil {
+		if err := opts.decrypt.apply(copyOpts); err != nil {
+			return err
+		}
+	}
+	if opts.sign != nil {
+		if err := opts.sign.apply(copyOpts, os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+		if opts.sign.signBySigstorePrivateKey != "" {
+			cleanup, err := enableSigstoreAttachments(destCtx, destRef)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+		}
+	}
+
+	// Blobs of registry images are retried one by one, so that a failure does not restart the whole copy;
+	// the copy itself is retried after other failures, e.g. reading a manifest.
+	// Bandwidth limits apply to the blobs read from the source registry, as transferred. Blobs written to
+	// a registry can't be limited without losing its private features, e.g. cross-repository blob mounts,
+	// so when only the destination is a registry, the limits apply to the blobs read from the local source.
+	copyPolicyContext := policyContext
+	switch {
+	case srcRef.Transport() == docker.Transport && (opts.retryOpts.MaxRetry > 0 || len(limiters) != 0):
+		srcRef = throttle.WrapReference(retry.WrapReference(srcRef, policyContext, opts.retryOpts), limiters)
+		// The wrapped sources check their manifests with policyContext themselves.
+		copyPolicyContext, err = signature.NewPolicyContext(&signature.Policy{Default: signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()}})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := copyPolicyContext.Destroy(); err != nil {
+				retErr = noteCloseFailure(retErr, "tearing down policy context", err)
+			}
+		}()
+	case srcRef.Transport() != docker.Transport:
+		srcRef = throttle.WrapReference(srcRef, limiters)
+	}
+	rateLimit := throttle.Limit(limiters)
+
 	return retry.IfNecessary(ctx, func() error {
-		_, err := copy.Image(ctx, policyContext, destRef, srcRef, &copy.Options{
-			ReportWriter:          stdout,
-			SourceCtx:             sourceCtx,
-			DestinationCtx:        destCtx,
-			ForceManifestMIMEType: manifestType,
-			ImageListSelection:    copy.CopySystemImage,
-		})
+		if err := restart(); err != nil {
+			return err
+		}
+		var err error
+		if emitter != nil {
+			_, err = emitter.copyImage(ctx, copyPolicyContext, destRef, srcRef, copyOpts, rateLimit)
+		} else {
+			_, err = copy.Image(ctx, copyPolicyContext, destRef, srcRef, copyOpts)
+		}
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/transports"
+	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/progress"
+	"gopull/pkgs/retry"
+)
+
+type cpOptions struct {
+	global    *globalOptions
+	image     *imageOptions
+	retryOpts *retry.Options
+}
+
+func cpCmd(global *globalOptions) *cobra.Command {
+	sharedFlags, sharedOpts := sharedImageFlags()
+	imageFlags, imageOpts := imageFlags(global, sharedOpts, nil, "", "")
+	retryFlags, retryOpts := retryFlags()
+	opts := cpOptions{
+		global:    global,
+		image:     imageOpts,
+		retryOpts: retryOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "cp [command options] IMAGE-NAME:PATH LOCAL-PATH",
+		Short: i18n.T("Copy a file out of image IMAGE-NAME"),
+		Long: fmt.Sprintf(i18n.T(`Copy the file at the absolute PATH in the filesystem of "IMAGE-NAME" to LOCAL-PATH.
+If LOCAL-PATH is an existing directory, the file is copied into it.
+
+Only the layers down to the one containing PATH are downloaded.
+
+"IMAGE-NAME" may be a registry reference, a "transport:details" name, or a path
+to a docker-archive or oci-archive file or an OCI layout directory.
+
+Supported transports:
+%s
+`), strings.Join(transports.ListNames(), ", ")),
+		RunE: commandAction(opts.run),
+		Example: `gopull cp alpine:/etc/os-release .
+gopull cp redis:7:/usr/local/bin/redis-server ./redis-server
+gopull cp redis.tar:/etc/passwd /tmp/passwd`,
+		ValidArgsFunction: autocompleteSupportedTransports,
+	}
+	adjustUsage(cmd)
+	flags := cmd.Flags()
+	flags.AddFlagSet(&sharedFlags)
+	flags.AddFlagSet(&imageFlags)
+	flags.AddFlagSet(&retryFlags)
+	return cmd
+}
+
+// splitImagePath splits an IMAGE-NAME:PATH argument; PATH must be absolute.
+// Image names may contain colons themselves (tags, transports, ports), so the last ":/" is the separator.
+func splitImagePath(arg string) (string, string, error) {
+	i := strings.LastIndex(arg, ":/")
+	if i <= 0 || strings.HasPrefix(arg[i:], "://") {
+		return "", "", i18n.Errorf("%q is not in the IMAGE-NAME:PATH format, with an absolute PATH", arg)
+	}
+	return arg[:i], arg[i+1:], nil
+}
+
+func (opts *cpOptions) run(args []string, stdout io.Writer) (retErr error) {
+	if len(args) != 2 {
+		return errorShouldDisplayUsage{i18n.New("Exactly two arguments expected")}
+	}
+	imageName, filePath, err := splitImagePath(args[0])
+	if err != nil {
+		return err
+	}
+	dest := args[1]
+	if fi, err := os.Stat(dest); err == nil && fi.IsDir() {
+		dest = filepath.Join(dest, path.Base(filePath))
+	}
+
+	ctx, cancel := opts.global.commandTimeoutContext()
+	defer cancel()
+
+	src, img, err := openSourceImage(ctx, opts.image, imageName, opts.retryOpts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing image", err)
+		}
+	}()
+
+	return readImageFile(ctx, src, img.LayerInfos(), opts.retryOpts, filePath, func(hdr *tar.Header, r io.Reader) (retErr error) {
+		f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, hdr.FileInfo().Mode().Perm())
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := f.Close(); err != nil && retErr == nil {
+				retErr = err
+			}
+			if retErr != nil {
+				os.Remove(dest)
+			}
+		}()
+		if _, err := io.Copy(f, r); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, i18n.T("Copied %s (%s) to %s\n"), filePath, progress.HumanSize(hdr.Size), dest)
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"github.com/containers/image/v5/copy"
+	encconfig "github.com/containers/ocicrypt/config"
+	enchelpers "github.com/containers/ocicrypt/helpers"
+	"github.com/spf13/pflag"
+
+	"gopull/pkgs/i18n"
+)
+
+// encryptionOptions collects CLI flags for encrypting the layers of the destination image while copying (ocicrypt).
+type encryptionOptions struct {
+	encryptionKeys []string // Recipients as PROTOCOL:KEY, e.g. jwe:/path/to/pub.pem
+	encryptLayers  []int    // Indexes of the layers to encrypt, negative from the last one; all layers if empty
+}
+
+// encryptionFlags prepares a collection of CLI flags writing into encryptionOptions, and the managed encryptionOptions structure.
+func encryptionFlags() (pflag.FlagSet, *encryptionOptions) {
+	opts := encryptionOptions{}
+	fs := pflag.FlagSet{}
+	fs.StringArrayVar(&opts.encryptionKeys, "encryption-key", nil, i18n.T("Encrypt the layers for `PROTOCOL:KEY`, e.g. jwe:pub.pem, pkcs7:cert.pem or pgp:user@example.com (can be repeated)"))
+	fs.IntSliceVar(&opts.encryptLayers, "encrypt-layer", nil, i18n.T("Only encrypt the layers at `INDEXES`, 0 for the first layer, -1 for the last one (default all layers)"))
+	return fs, &opts
+}
+
+// apply sets the encryption options of options.
+func (opts *encryptionOptions) apply(options *copy.Options) error {
+	if len(opts.encryptionKeys) == 0 {
+		if len(opts.encryptLayers) != 0 {
+			return i18n.New("--encrypt-layer can only be used with --encryption-key")
+		}
+		return nil
+	}
+	cc, err := enchelpers.CreateCryptoConfig(opts.encryptionKeys, nil)
+	if err != nil {
+		return i18n.Errorf("invalid --encryption-key: %w", err)
+	}
+	layers := append([]int{}, opts.encryptLayers...)
+	options.OciEncryptLayers = &layers
+	options.OciEncryptConfig = encconfig.CombineCryptoConfigs([]encconfig.CryptoConfig{cc}).EncryptConfig
+	return nil
+}
+
+// decryptionOptions collects CLI flags for decrypting the layers of the source image while copying (ocicrypt).
+type decryptionOptions struct {
+	decryptionKeys []string // Private keys as PATH[:PASSWORD]
+}
+
+// decryptionFlags prepares a collection of CLI flags writing into decryptionOptions, and the managed decryptionOptions structure.
+func decryptionFlags() (pflag.FlagSet, *decryptionOptions) {
+	opts := decryptionOptions{}
+	fs := pflag.FlagSet{}
+	fs.StringArrayVar(&opts.decryptionKeys, "decryption-key", nil, i18n.T("Decrypt the layers using the private key at `PATH[:PASSWORD]` (can be repeated)"))
+	return fs, &opts
+}
+
+// apply sets the decryption options of options.
+func (opts *decryptionOptions) apply(options *copy.Options) error {
+	if len(opts.decryptionKeys) == 0 {
+		return nil
+	}
+	cc, err := enchelpers.CreateCryptoConfig(nil, opts.decryptionKeys)
+	if err != nil {
+		return i18n.Errorf("invalid --decryption-key: %w", err)
+	}
+	options.OciDecryptConfig = encconfig.CombineCryptoConfigs([]encconfig.CryptoConfig{cc}).DecryptConfig
+	return nil
+}
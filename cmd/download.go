@@ -4,19 +4,29 @@ import (
 	"fmt"
 	"gopull/pkgs/image"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 
 	commonFlag "github.com/containers/common/pkg/flag"
+	"github.com/containers/image/v5/docker/archive"
+	ociarchive "github.com/containers/image/v5/oci/archive"
 	"github.com/containers/image/v5/transports"
-	"github.com/containers/image/v5/transports/alltransports"
 	"github.com/containers/image/v5/types"
 	"github.com/distribution/reference"
 	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
 )
 
 type downloadOptions struct {
 	*pullOptions
-	outFile string
+	outFile      string // Path of the archive, instead of one derived from the image name
+	outputDir    string // Directory of the archive, if its path is relative
+	nameTemplate string // Template of the archive file name
+
+	srcRef    types.ImageReference // The source being downloaded, set by buildSrcRef
+	sourceCtx *types.SystemContext // The context of srcRef
 }
 
 func download(global *globalOptions) *cobra.Command {
@@ -25,6 +35,10 @@ func download(global *globalOptions) *cobra.Command {
 	srcFlags, srcOpts := imageFlags(global, sharedOpts, deprecatedTLSVerifyOpt, "src-", "screds")
 	destFlags, destOpts := imageDestFlags(global, sharedOpts, deprecatedTLSVerifyOpt, "dest-", "dcreds")
 	retryFlags, retryOpts := retryFlags()
+	verifyFlags, verifyOpts := verifyFlags()
+	rateLimitFlags, rateLimitOpts := rateLimitFlags(global)
+	encryptFlags, encryptOpts := encryptionFlags()
+	outputFlags, outputOpts := outputFileFlags()
 	opts := downloadOptions{
 		pullOptions: &pullOptions{
 			copyOptions: &copyOptions{
@@ -33,21 +47,33 @@ func download(global *globalOptions) *cobra.Command {
 				srcImage:            srcOpts,
 				destImage:           destOpts,
 				retryOpts:           retryOpts,
+				verify:              verifyOpts,
+				rateLimit:           rateLimitOpts,
+				encrypt:             encryptOpts,
+				output:              outputOpts,
+				resolveShortNames:   true,
 			},
 		},
 	}
 	cmd := &cobra.Command{
 		Use:   "download [command options] IMAGE ",
-		Short: "download an image",
-		Long: fmt.Sprintf(`Container "IMAGE-NAME" uses a "transport":"details" format.
+		Short: i18n.T("download an image"),
+		Long: fmt.Sprintf(i18n.T(`Container "IMAGE-NAME" uses a "transport":"details" format.
 
 Supported transports:
 %s
 
 See skopeo(1) section "IMAGE NAMES" for the expected format
-`, strings.Join(transports.ListNames(), ", ")),
-		RunE:              commandAction(opts.run),
-		Example:           `gopull download redis`,
+`), strings.Join(transports.ListNames(), ", ")),
+		RunE: commandAction(opts.run),
+		Example: `gopull download redis
+gopull download --verify-key cosign.pub registry.example.com/app:1.0
+gopull download --verify-gpg pubring.gpg registry.example.com/app:1.0
+gopull download --progress json redis > redis-progress.ndjson
+gopull download --limit-rate 5M --registry-limit-rate registry.example.com=1M redis
+gopull download --output-dir images --name-template '{{.Registry}}_{{.Repository}}_{{.Tag}}_{{.Arch}}.tar' redis
+gopull download --skip-existing --output-dir images redis:7
+gopull download --encryption-key jwe:pub.pem -o redis.enc.tar redis:7`,
 		ValidArgsFunction: autocompleteSupportedTransports,
 	}
 	adjustUsage(cmd)
@@ -57,50 +83,125 @@ See skopeo(1) section "IMAGE NAMES" for the expected format
 	flags.AddFlagSet(&srcFlags)
 	flags.AddFlagSet(&destFlags)
 	flags.AddFlagSet(&retryFlags)
-	flags.BoolVarP(&opts.quiet, "quiet", "q", false, "Suppress output information when copying images")
-	flags.VarP(commonFlag.NewOptionalStringValue(&opts.format), "format", "f", `MANIFEST TYPE (oci, v2s1, or v2s2) to use in the destination (default is manifest type of source, with fallbacks)`)
-	flags.StringVarP(&opts.outFile, "outfile", "o", "", "Read a passphrase for signing an image from `PATH`")
-	flags.StringVarP(&opts.addTag, "tag", "t", "", "set dest tag ")
+	flags.AddFlagSet(&verifyFlags)
+	flags.AddFlagSet(&rateLimitFlags)
+	flags.AddFlagSet(&encryptFlags)
+	flags.AddFlagSet(&outputFlags)
+	flags.BoolVarP(&opts.quiet, "quiet", "q", false, i18n.T("Suppress output information when copying images"))
+	flags.VarP(commonFlag.NewOptionalStringValue(&opts.format), "format", "f", i18n.T(`MANIFEST TYPE (oci, v2s1, or v2s2) to use in the destination (default is manifest type of source, with fallbacks)`))
+	flags.StringVarP(&opts.outFile, "outfile", "o", "", i18n.T("Write the archive to `PATH` (default NAME.TAG.tar)"))
+	flags.StringVar(&opts.outputDir, "output-dir", "", i18n.T("Write the archive to `DIR`, created if needed, instead of the working directory"))
+	flags.StringVar(&opts.nameTemplate, "name-template", "", i18n.T("Go `TEMPLATE` of the archive file name, using .Registry, .Repository, .Name, .Tag, .Digest, .ShortDigest, .OS, .Arch, .Variant and .Platform"))
+	flags.StringVarP(&opts.addTag, "tag", "t", "", i18n.T("set dest tag "))
+	flags.BoolVar(&opts.dryRun, "dry-run", false, i18n.T("Report the blobs, tags and files the download would transfer and write, without writing anything"))
+	flags.StringVar(&opts.progress, "progress", progressText, i18n.T("Progress output `FORMAT`, text or json (newline-delimited events on standard output)"))
 	return cmd
 }
 
 func (opts *downloadOptions) run(args []string, stdout io.Writer) error {
+	if opts.outFile != "" && opts.nameTemplate != "" {
+		return i18n.New("--outfile and --name-template can not be used together")
+	}
+	// An existing archive is recognized by the config of its image, which encryption doesn't change.
+	if opts.output.skipExisting && len(opts.encrypt.encryptionKeys) != 0 {
+		return i18n.New("--skip-existing can not be used with --encryption-key, an existing archive can't be checked to be encrypted for the same recipients")
+	}
+	if opts.nameTemplate != "" {
+		if _, err := parseNameTemplate(opts.nameTemplate); err != nil {
+			return err
+		}
+	}
 	return opts.pullOptions.execCopy(args, stdout, opts.buildSrcRef, opts.buildDestRef)
 }
 
+// buildSrcRef is pullOptions.buildSrcRef, recording the source for --name-template.
+func (opts *downloadOptions) buildSrcRef(imageName string) (types.ImageReference, *types.SystemContext, error) {
+	srcRef, sourceCtx, err := opts.pullOptions.buildSrcRef(imageName)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts.srcRef, opts.sourceCtx = srcRef, sourceCtx
+	return srcRef, sourceCtx, nil
+}
+
+// archivePath returns the path of the archive of parsedImage, the image name, and creates its directory
+// unless this is a dry run.
+func (opts *downloadOptions) archivePath(parsedImage image.ImageStruct) (string, error) {
+	var path string
+	switch {
+	case opts.outFile != "":
+		path = opts.outFile
+	case opts.nameTemplate != "":
+		tmpl, err := parseNameTemplate(opts.nameTemplate)
+		if err != nil {
+			return "", err
+		}
+		ctx, cancel := opts.global.commandTimeoutContext()
+		defer cancel()
+		path, err = executeNameTemplate(tmpl, newTarNameData(ctx, parsedImage, opts.srcRef, opts.sourceCtx, opts.retryOpts))
+		if err != nil {
+			return "", err
+		}
+	default:
+		path = getDefaultImageTarName(parsedImage)
+	}
+	if opts.outputDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(opts.outputDir, path)
+	}
+	if opts.outputDir != "" && !opts.dryRun {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", i18n.Errorf("creating output directory: %w", err)
+		}
+	}
+	return path, nil
+}
+
 func (opts *downloadOptions) buildDestRef(imageName string) (types.ImageReference, *types.SystemContext, error) {
 
 	parsedImage, err := image.ParseImageStr(imageName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parse image faild name %s: %v", imageName, err)
+		return nil, nil, invalidReferenceError{i18n.Errorf("failed to parse image name %s: %v", imageName, err)}
 	}
 
-	dest := "docker-archive:"
-	if opts.outFile != "" {
-		dest += opts.outFile
-	} else {
-		dest += getDefaultImageTarName(parsedImage)
+	path, err := opts.archivePath(parsedImage)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	destRef, err := alltransports.ParseImageName(dest)
+	tag, err := opts.global.expandAlias(opts.addTag)
 	if err != nil {
-		return nil, nil, fmt.Errorf("invalid destination name %s: %v", dest, err)
+		return nil, nil, err
 	}
-
-	destCtx, err := opts.destImage.newSystemContext()
+	destTag, err := buildDestTag(parsedImage, tag)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	var destTag string
+	// Not parsed as a transport:details name, in which a colon would start a reference.
+	newReference := func(path string) (types.ImageReference, error) {
+		return archive.NewReference(path, nil)
+	}
+	encrypted := len(opts.encrypt.encryptionKeys) != 0
+	if encrypted {
+		// docker-archive: only supports Docker manifests, which can't describe encrypted layers.
+		newReference = func(path string) (types.ImageReference, error) {
+			return ociarchive.NewReference(path, destTag)
+		}
+	}
+	destRef, err := newReference(path)
+	if err != nil {
+		return nil, nil, invalidReferenceError{i18n.Errorf("invalid destination name %s: %v", path, err)}
+	}
+	opts.output.path, opts.output.newReference = path, newReference
 
-	destTag, err = buildDestTag(parsedImage, opts.addTag)
+	destCtx, err := opts.destImage.newSystemContext()
 	if err != nil {
 		return nil, nil, err
 	}
-
-	if err := addTag(destCtx, destTag); err != nil {
-		return nil, nil, fmt.Errorf(`添加目标tag失败,name:%s err:%v`, opts.addTag, err)
+	if !encrypted {
+		if err := addTag(destCtx, destTag); err != nil {
+			return nil, nil, i18n.Errorf("failed to add destination tag %s: %w", opts.addTag, err)
+		}
 	}
 	return destRef, destCtx, nil
 }
@@ -108,11 +209,11 @@ func (opts *downloadOptions) buildDestRef(imageName string) (types.ImageReferenc
 func addTag(sysCtx *types.SystemContext, tag string) error {
 	ref, err := reference.ParseNormalizedNamed(tag)
 	if err != nil {
-		return fmt.Errorf("error parsing tag %v", err)
+		return invalidReferenceError{i18n.Errorf("error parsing tag %v", err)}
 	}
 	namedTagged, isNamedTagged := ref.(reference.NamedTagged)
 	if !isNamedTagged {
-		return fmt.Errorf("dest must be a tagged reference")
+		return i18n.Errorf("dest must be a tagged reference")
 	}
 	sysCtx.DockerArchiveAdditionalTags = append(sysCtx.DockerArchiveAdditionalTags, namedTagged)
 	return nil
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/daemon"
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/pkg/blobinfocache/none"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/types"
+	dockerclient "github.com/docker/docker/client"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/progress"
+)
+
+// plannedBlob is a blob a copy would transfer, unless it already exists at the destination.
+type plannedBlob struct {
+	Digest    digest.Digest
+	Kind      string // "config" or "layer"
+	Size      int64  // -1 if unknown
+	SizeHuman string
+	Exists    bool // Already present at the destination
+}
+
+// planCopy reports what copying srcRef to destRef would do: the blobs to transfer and their sizes,
+// which of them already exist at the destination, and the resulting tags and files.
+// Nothing is written to the destination.
+func (opts *copyOptions) planCopy(ctx context.Context, policyContext *signature.PolicyContext, srcRef types.ImageReference, sourceCtx *types.SystemContext,
+	destRef types.ImageReference, destCtx *types.SystemContext, stdout io.Writer) (retErr error) {
+	src, img, err := openImage(ctx, sourceCtx, srcRef, opts.retryOpts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing image", err)
+		}
+	}()
+	if allowed, err := policyContext.IsRunningImageAllowed(ctx, image.UnparsedInstance(src, nil)); !allowed {
+		return i18n.Errorf("Source image rejected: %w", err)
+	}
+
+	rawManifest, manifestType, err := img.Manifest(ctx)
+	if err != nil {
+		return i18n.Errorf("Error retrieving manifest for image: %w", err)
+	}
+	manifestDigest, err := manifest.Digest(rawManifest)
+	if err != nil {
+		return i18n.Errorf("Error computing manifest digest: %w", err)
+	}
+
+	layers := img.LayerInfos()
+	blobs := make([]plannedBlob, 0, len(layers)+1)
+	if config := img.ConfigInfo(); config.Digest != "" {
+		blobs = append(blobs, plannedBlob{Digest: config.Digest, Kind: "config", Size: config.Size})
+	}
+	for _, layer := range layers {
+		blobs = append(blobs, plannedBlob{Digest: layer.Digest, Kind: "layer", Size: layer.Size})
+	}
+
+	fmt.Fprintf(stdout, i18n.T("Source: %s\n"), transports.ImageName(srcRef))
+	fmt.Fprintf(stdout, i18n.T("Manifest: %s (%s)\n"), manifestDigest, manifestType)
+	fmt.Fprintf(stdout, i18n.T("Destination: %s\n"), transports.ImageName(destRef))
+	for _, tag := range plannedTags(destRef, destCtx) {
+		fmt.Fprintf(stdout, i18n.T("Tag: %s\n"), tag)
+	}
+
+	switch destRef.Transport().Name() {
+	case docker.Transport.Name():
+		if err := markBlobsInRegistry(ctx, destRef, destCtx, blobs); err != nil {
+			logrus.Warnf("Could not check which blobs exist at the destination: %v", err)
+		}
+	case daemon.Transport.Name():
+		if err := markLayersInDaemon(ctx, img, destRef, destCtx, blobs); err != nil {
+			logrus.Warnf("Could not check which layers exist in the docker daemon: %v", err)
+		}
+	case "docker-archive", "oci-archive":
+		path, _, _ := strings.Cut(destRef.StringWithinTransport(), ":")
+		if opts.output == nil || opts.output.path == "" {
+			if fi, err := os.Stat(path); err == nil && fi.Size() != 0 {
+				fmt.Fprintf(stdout, i18n.T("Output file: %s (already exists, the copy would fail)\n"), path)
+			} else {
+				fmt.Fprintf(stdout, i18n.T("Output file: %s\n"), path)
+			}
+			break
+		}
+		// The archive is written as outputFileOptions.begin would.
+		action, err := opts.output.existingArchive(ctx, srcRef, sourceCtx, opts.retryOpts)
+		switch {
+		case err != nil:
+			fmt.Fprintf(stdout, i18n.T("Output file: %s (the copy would fail: %v)\n"), opts.output.path, err)
+		case action == archiveSkip:
+			fmt.Fprintf(stdout, i18n.T("Output file: %s (already contains the image, the copy would be skipped)\n"), opts.output.path)
+		case action == archiveReplace:
+			fmt.Fprintf(stdout, i18n.T("Output file: %s (already exists, it would be replaced)\n"), opts.output.path)
+		default:
+			fmt.Fprintf(stdout, i18n.T("Output file: %s\n"), opts.output.path)
+		}
+	}
+	fmt.Fprintln(stdout)
+
+	var transferSize, existingSize int64
+	existing := 0
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("BLOB\tTYPE\tSIZE\tSTATUS"))
+	for i := range blobs {
+		b := &blobs[i]
+		b.SizeHuman = progress.HumanSize(b.Size)
+		status := i18n.T("transfer")
+		if b.Exists {
+			status = i18n.T("exists")
+			existing++
+			existingSize += max(b.Size, 0)
+		} else {
+			transferSize += max(b.Size, 0)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.Digest, i18n.T(b.Kind), b.SizeHuman, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, i18n.T("To transfer: %d blobs, %s\n"), len(blobs)-existing, progress.HumanSize(transferSize))
+	fmt.Fprintf(stdout, i18n.T("Already at destination: %d blobs, %s\n"), existing, progress.HumanSize(existingSize))
+	_, err = fmt.Fprintf(stdout, i18n.T("Estimated size on disk: %s\n"), newImageSize(layers).EstimatedUncompressedSizeHuman)
+	return err
+}
+
+// plannedTags returns the tags the image would have at destRef.
+func plannedTags(destRef types.ImageReference, destCtx *types.SystemContext) []string {
+	var res []string
+	if named := destRef.DockerReference(); named != nil {
+		res = append(res, named.String())
+	}
+	if destRef.Transport().Name() == "docker-archive" {
+		for _, tag := range destCtx.DockerArchiveAdditionalTags {
+			res = append(res, tag.String())
+		}
+	}
+	return res
+}
+
+// markBlobsInRegistry sets Exists for the blobs which are already in the destination repository.
+// Only the repository itself is checked, so this does not try to mount blobs from other repositories.
+func markBlobsInRegistry(ctx context.Context, destRef types.ImageReference, destCtx *types.SystemContext, blobs []plannedBlob) (retErr error) {
+	dest, err := destRef.NewImageDestination(ctx, destCtx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dest.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing destination", err)
+		}
+	}()
+	for i := range blobs {
+		reused, _, err := dest.TryReusingBlob(ctx, types.BlobInfo{Digest: blobs[i].Digest, Size: blobs[i].Size}, none.NoCache, false)
+		if err != nil {
+			return err
+		}
+		blobs[i].Exists = reused
+	}
+	return nil
+}
+
+// markLayersInDaemon sets Exists for the config and layers already stored by the docker daemon for the destination tag.
+// The daemon stores layers as a stack, so a layer is only reused if all layers below it are too.
+func markLayersInDaemon(ctx context.Context, img types.Image, destRef types.ImageReference, destCtx *types.SystemContext, blobs []plannedBlob) error {
+	named := destRef.DockerReference()
+	if named == nil {
+		return nil // An image ID destination, nothing to compare to
+	}
+	config, err := img.OCIConfig(ctx)
+	if err != nil {
+		return err
+	}
+	clientOpts := []dockerclient.Opt{dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation()}
+	if destCtx != nil && destCtx.DockerDaemonHost != "" {
+		clientOpts = append(clientOpts, dockerclient.WithHost(destCtx.DockerDaemonHost))
+	}
+	client, err := dockerclient.NewClientWithOpts(clientOpts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	existing, _, err := client.ImageInspectWithRaw(ctx, named.String())
+	if err != nil {
+		if dockerclient.IsErrNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	layer := 0
+	for i := range blobs {
+		switch blobs[i].Kind {
+		case "config":
+			blobs[i].Exists = existing.ID == blobs[i].Digest.String()
+		case "layer":
+			if layer < len(config.RootFS.DiffIDs) && layer < len(existing.RootFS.Layers) &&
+				existing.RootFS.Layers[layer] == config.RootFS.DiffIDs[layer].String() &&
+				(layer == 0 || blobs[i-1].Kind != "layer" || blobs[i-1].Exists) {
+				blobs[i].Exists = true
+			}
+			layer++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"gopull/pkgs/i18n"
+)
+
+// envPrefix is the prefix of the environment variables setting options.
+const envPrefix = "GOPULL_"
+
+// envAnnotation is the pflag.Flag annotation listing the environment variable setting the option.
+const envAnnotation = "gopull_env"
+
+// envName returns the environment variable setting the option name, e.g. GOPULL_DEST_TLS_VERIFY for --dest-tls-verify.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// bindEnvironment allows setting the visible options in fs through GOPULL_ environment variables, and lists
+// the variables in the usage of the options.
+func bindEnvironment(fs *pflag.FlagSet) {
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || f.Annotations[envAnnotation] != nil {
+			return
+		}
+		env := envName(f.Name)
+		if f.Annotations == nil {
+			f.Annotations = map[string][]string{}
+		}
+		f.Annotations[envAnnotation] = []string{env}
+		f.Usage = fmt.Sprintf(i18n.T("%s (env %s)"), f.Usage, env)
+	})
+}
+
+// applyEnvironment sets the options of cmd bound to environment variables, which are not set on the command line,
+// to the values of the variables.
+// Two options bound to the same variable, and GOPULL_ variables which set no option, are reported.
+func applyEnvironment(cmd *cobra.Command) error {
+	bound := map[string]string{} // Option names, indexed by environment variable
+	var conflict error
+	visitAllFlags(cmd.Root(), func(f *pflag.Flag) {
+		for _, env := range f.Annotations[envAnnotation] {
+			if name, ok := bound[env]; ok && name != f.Name && conflict == nil {
+				conflict = i18n.Errorf("options --%s and --%s are both set by environment variable %s", name, f.Name, env)
+			}
+			bound[env] = f.Name
+		}
+	})
+	if conflict != nil {
+		return conflict
+	}
+	for _, entry := range os.Environ() {
+		env, _, _ := strings.Cut(entry, "=")
+		if _, ok := bound[env]; strings.HasPrefix(env, envPrefix) && !ok {
+			logrus.Warnf("Environment variable %s does not set any option", env)
+		}
+	}
+
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		for _, env := range f.Annotations[envAnnotation] {
+			value, ok := os.LookupEnv(env)
+			if !ok || err != nil {
+				continue
+			}
+			if f.Changed {
+				logrus.Debugf("Ignoring environment variable %s, --%s is set on the command line", env, f.Name)
+				continue
+			}
+			if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+				err = i18n.Errorf("invalid value %q of environment variable %s: %w", value, env, setErr)
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// envTestCommand returns the "copy" subcommand of a root command with a --debug persistent option, and its own
+// --retry-times and --dest-tls-verify options, all bound to environment variables, with args parsed.
+func envTestCommand(t *testing.T, args ...string) (*cobra.Command, *bool, *int, *bool) {
+	t.Helper()
+	root := &cobra.Command{Use: "gopull"}
+	debug := root.PersistentFlags().Bool("debug", false, "enable debug output")
+	bindEnvironment(root.PersistentFlags())
+	copyCmd := &cobra.Command{Use: "copy"}
+	retryTimes := copyCmd.Flags().Int("retry-times", 0, "number of retries")
+	destTLSVerify := copyCmd.Flags().Bool("dest-tls-verify", true, "verify certificates")
+	bindEnvironment(copyCmd.Flags())
+	root.AddCommand(copyCmd)
+	if err := copyCmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return copyCmd, debug, retryTimes, destTLSVerify
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	t.Setenv("GOPULL_DEBUG", "true")
+	t.Setenv("GOPULL_RETRY_TIMES", "3")
+	t.Setenv("GOPULL_DEST_TLS_VERIFY", "false")
+	cmd, debug, retryTimes, destTLSVerify := envTestCommand(t, "--retry-times", "5")
+	if err := applyEnvironment(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if !*debug {
+		t.Error("--debug not set by GOPULL_DEBUG")
+	}
+	if *destTLSVerify {
+		t.Error("--dest-tls-verify not set by GOPULL_DEST_TLS_VERIFY")
+	}
+	if *retryTimes != 5 {
+		t.Errorf("--retry-times %d, expected 5 from the command line", *retryTimes)
+	}
+
+	t.Setenv("GOPULL_RETRY_TIMES", "many")
+	cmd, _, _, _ = envTestCommand(t)
+	if err := applyEnvironment(cmd); err == nil || !strings.Contains(err.Error(), "GOPULL_RETRY_TIMES") {
+		t.Errorf("invalid value: %v, expected an error naming GOPULL_RETRY_TIMES", err)
+	}
+}
+
+func TestApplyEnvironmentUnknownVariable(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.StandardLogger()
+	out := logger.Out
+	logger.SetOutput(&buf)
+	defer logger.SetOutput(out)
+
+	t.Setenv("GOPULL_RETRY_TIME", "3")
+	cmd, _, retryTimes, _ := envTestCommand(t)
+	if err := applyEnvironment(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if *retryTimes != 0 {
+		t.Errorf("--retry-times %d, expected 0", *retryTimes)
+	}
+	if !strings.Contains(buf.String(), "GOPULL_RETRY_TIME does not set any option") {
+		t.Errorf("unknown variable not reported, logged %q", buf.String())
+	}
+}
+
+func TestApplyEnvironmentConflict(t *testing.T) {
+	cmd, _, _, _ := envTestCommand(t)
+	other := &cobra.Command{Use: "sync"}
+	other.Flags().Bool("dest.tls.verify", true, "verify certificates") // Also GOPULL_DEST_TLS_VERIFY
+	bindEnvironment(other.Flags())
+	cmd.Root().AddCommand(other)
+	err := applyEnvironment(cmd)
+	if err == nil || !strings.Contains(err.Error(), "GOPULL_DEST_TLS_VERIFY") {
+		t.Errorf("%v, expected a conflict on GOPULL_DEST_TLS_VERIFY", err)
+	}
+}
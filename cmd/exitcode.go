@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/signature"
+	"github.com/docker/distribution/registry/api/errcode"
+	errcodev2 "github.com/docker/distribution/registry/api/v2"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
+
+	"gopull/pkgs/retry"
+)
+
+// Values of --error-format.
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// errorCategory is a class of failures, with the exit code reporting it.
+// The exit codes are part of the command line interface, documented in the README; don’t change them.
+type errorCategory struct {
+	Name     string
+	ExitCode int
+}
+
+var (
+	errorCategoryOther            = errorCategory{"error", 1}
+	errorCategoryNotFound         = errorCategory{"not-found", 2}
+	errorCategoryAuth             = errorCategory{"auth", 3}
+	errorCategoryNetwork          = errorCategory{"network", 4}
+	errorCategoryRejected         = errorCategory{"rejected", 5}
+	errorCategoryInvalidReference = errorCategory{"invalid-reference", 6}
+	errorCategoryDestination      = errorCategory{"destination", 7}
+	errorCategoryDaemon           = errorCategory{"daemon-unreachable", 8}
+	errorCategoryInterrupted      = errorCategory{"interrupted", 130} // As shells report SIGINT
+)
+
+// invalidReferenceError is returned for image names and references which can not be parsed.
+type invalidReferenceError struct {
+	err error
+}
+
+func (e invalidReferenceError) Error() string {
+	return e.err.Error()
+}
+
+func (e invalidReferenceError) Unwrap() error {
+	return e.err
+}
+
+// destinationErrorPrefixes are the prefixes containers/image adds to errors writing to the destination of a copy.
+var destinationErrorPrefixes = []string{
+	"initializing destination",
+	"writing blob",
+	"writing manifest",
+	"writing signatures",
+	"committing the finished image",
+}
+
+// classifyError returns the category of err.
+// Like isNotFoundImageError, this is a heuristic; containers/image does not report most failures as typed errors.
+func classifyError(err error) errorCategory {
+	var candidates candidatesError
+	if errors.As(err, &candidates) {
+		// The candidates of a short name failed; report their category if it is the same for all.
+		res := classifyError(candidates.errs[0])
+		for _, e := range candidates.errs[1:] {
+			if classifyError(e) != res {
+				return errorCategoryOther
+			}
+		}
+		return res
+	}
+	// The root context is only cancelled by handleInterrupts, but most commands return the error of the
+	// cancelled operation as is, without the interruptedError cause.
+	if errors.Is(err, context.Canceled) {
+		return errorCategoryInterrupted
+	}
+	var unauthorized docker.ErrUnauthorizedForCredentials
+	var policyErr signature.PolicyRequirementError
+	var invalidSig signature.InvalidSignatureError
+	var invalidRef invalidReferenceError
+	switch {
+	case errors.As(err, &policyErr) || errors.As(err, &invalidSig):
+		return errorCategoryRejected
+	case errors.As(err, &unauthorized) || hasErrorCode(err, errcode.ErrorCodeUnauthorized, errcode.ErrorCodeDenied):
+		return errorCategoryAuth
+	case isNotFoundImageError(err) || hasErrorCode(err, errcodev2.ErrorCodeNameUnknown) || dockerclient.IsErrNotFound(err):
+		return errorCategoryNotFound
+	case errors.As(err, &invalidRef):
+		return errorCategoryInvalidReference
+	case dockerclient.IsErrConnectionFailed(err) || strings.Contains(err.Error(), "Cannot connect to the Docker daemon"):
+		return errorCategoryDaemon
+	case errors.Is(err, context.DeadlineExceeded) || retry.IsErrorRetryable(err):
+		return errorCategoryNetwork
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		for _, prefix := range destinationErrorPrefixes {
+			if strings.HasPrefix(e.Error(), prefix) {
+				return errorCategoryDestination
+			}
+		}
+	}
+	return errorCategoryOther
+}
+
+// hasErrorCode returns true if err is a registry error with one of codes.
+func hasErrorCode(err error, codes ...errcode.ErrorCode) bool {
+	var ec errcode.ErrorCoder
+	if !errors.As(err, &ec) {
+		return false
+	}
+	for _, code := range codes {
+		if ec.ErrorCode() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonError is the --error-format json output.
+type jsonError struct {
+	Error struct {
+		Category string `json:"category"`
+		ExitCode int    `json:"exitCode"`
+		Message  string `json:"message"`
+	} `json:"error"`
+}
+
+// reportError writes err to stderr in format, and returns the exit code to use.
+func reportError(stderr io.Writer, err error, format string) int {
+	category := classifyError(err)
+	if format != errorFormatJSON {
+		logrus.StandardLogger().Log(logrus.FatalLevel, err)
+		return category.ExitCode
+	}
+	var out jsonError
+	out.Error.Category = category.Name
+	out.Error.ExitCode = category.ExitCode
+	out.Error.Message = err.Error()
+	enc := json.NewEncoder(stderr)
+	enc.SetEscapeHTML(false)
+	if encErr := enc.Encode(out); encErr != nil {
+		fmt.Fprintln(stderr, err)
+	}
+	return category.ExitCode
+}
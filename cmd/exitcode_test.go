@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/storage"
+	"github.com/docker/distribution/registry/api/errcode"
+	errcodev2 "github.com/docker/distribution/registry/api/v2"
+)
+
+func TestClassifyError(t *testing.T) {
+	notFound := errcode.Error{Code: errcodev2.ErrorCodeManifestUnknown, Message: "manifest unknown"}
+	auth := docker.ErrUnauthorizedForCredentials{Err: errors.New("invalid username/password")}
+	network := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	for _, c := range []struct {
+		name     string
+		err      error
+		expected errorCategory
+	}{
+		{"other", errors.New("something failed"), errorCategoryOther},
+		{"manifest unknown", fmt.Errorf("reading manifest latest: %w", notFound), errorCategoryNotFound},
+		{"no such image", fmt.Errorf("opening image: %w", storage.ErrNoSuchImage), errorCategoryNotFound},
+		{"unauthorized", fmt.Errorf("reading manifest: %w", auth), errorCategoryAuth},
+		{"denied", errcode.ErrorCodeDenied.WithMessage("requested access to the resource is denied"), errorCategoryAuth},
+		{"connection refused", fmt.Errorf("pinging registry: %w", network), errorCategoryNetwork},
+		{"timeout", fmt.Errorf("reading blob: %w", context.DeadlineExceeded), errorCategoryNetwork},
+		{"policy", fmt.Errorf("source image rejected: %w", signature.PolicyRequirementError("no signature")), errorCategoryRejected},
+		{"invalid reference", invalidReferenceError{errors.New("invalid reference format")}, errorCategoryInvalidReference},
+		{"destination", fmt.Errorf("writing blob: %w", syscall.ENOSPC), errorCategoryDestination},
+		{"daemon", errors.New("Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?"), errorCategoryDaemon},
+		{"interrupted", fmt.Errorf("%w: docker://alpine was not copied", interruptedError{}), errorCategoryInterrupted},
+		{"canceled", fmt.Errorf("reading blob: %w", context.Canceled), errorCategoryInterrupted},
+		{"candidates, same category", candidatesError{message: errors.New("all failed"), errs: []error{notFound, notFound}}, errorCategoryNotFound},
+		{"candidates, mixed categories", candidatesError{message: errors.New("all failed"), errs: []error{notFound, auth}}, errorCategoryOther},
+		{"candidates, wrapped", fmt.Errorf("initializing source: %w", candidatesError{message: errors.New("all failed"), errs: []error{auth, auth}}), errorCategoryAuth},
+	} {
+		if res := classifyError(c.err); res != c.expected {
+			t.Errorf("%s: %v, expected %v", c.name, res, c.expected)
+		}
+	}
+}
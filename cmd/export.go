@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/pkg/blobinfocache/none"
+	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/types"
+	"github.com/containers/storage/pkg/archive"
+	"github.com/containers/storage/pkg/unshare"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
+	"gopull/pkgs/rootfs"
+)
+
+type exportOptions struct {
+	global    *globalOptions
+	image     *imageOptions
+	retryOpts *retry.Options
+	to        string // Directory, or tar file, to write the root filesystem to
+}
+
+func exportCmd(global *globalOptions) *cobra.Command {
+	sharedFlags, sharedOpts := sharedImageFlags()
+	imageFlags, imageOpts := imageFlags(global, sharedOpts, nil, "", "")
+	retryFlags, retryOpts := retryFlags()
+	opts := exportOptions{
+		global:    global,
+		image:     imageOpts,
+		retryOpts: retryOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "export [command options] IMAGE-NAME --to PATH",
+		Short: i18n.T("Export the merged root filesystem of IMAGE-NAME"),
+		Long: fmt.Sprintf(i18n.T(`Apply the layers of "IMAGE-NAME" in order and write the resulting root filesystem
+to a directory, or to a tar file if PATH ends with ".tar" ("-" for standard output).
+No container engine is required.
+
+"IMAGE-NAME" may be a registry reference, a "transport:details" name, or a path
+to a docker-archive or oci-archive file or an OCI layout directory.
+
+Supported transports:
+%s
+`), strings.Join(transports.ListNames(), ", ")),
+		RunE: commandAction(opts.run),
+		Example: `gopull export alpine --to rootfs/
+gopull export redis.tar --to rootfs.tar`,
+		ValidArgsFunction: autocompleteSupportedTransports,
+	}
+	adjustUsage(cmd)
+	flags := cmd.Flags()
+	flags.StringVar(&opts.to, "to", "", i18n.T("write the root filesystem to `PATH`, a directory or a .tar file"))
+	flags.AddFlagSet(&sharedFlags)
+	flags.AddFlagSet(&imageFlags)
+	flags.AddFlagSet(&retryFlags)
+	return cmd
+}
+
+func (opts *exportOptions) run(args []string, stdout io.Writer) (retErr error) {
+	if len(args) != 1 {
+		return errorShouldDisplayUsage{i18n.New("Exactly one argument expected")}
+	}
+	if opts.to == "" {
+		return errorShouldDisplayUsage{i18n.New("--to is required")}
+	}
+	ctx, cancel := opts.global.commandTimeoutContext()
+	defer cancel()
+
+	src, img, err := openSourceImage(ctx, opts.image, args[0], opts.retryOpts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing image", err)
+		}
+	}()
+
+	layers := img.LayerInfos()
+	if opts.to == "-" {
+		return opts.exportTar(ctx, src, layers, stdout)
+	}
+	if strings.HasSuffix(opts.to, ".tar") {
+		if err := opts.exportTarFile(ctx, src, layers); err != nil {
+			return err
+		}
+	} else if err := opts.exportDir(ctx, src, layers); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, i18n.T("Exported %d layers to %s\n"), len(layers), opts.to)
+	return nil
+}
+
+// exportDir applies layers, bottom layer first, to the opts.to directory, which must be empty or not exist.
+func (opts *exportOptions) exportDir(ctx context.Context, src types.ImageSource, layers []types.BlobInfo) error {
+	if err := os.MkdirAll(opts.to, 0o755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(opts.to)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return i18n.Errorf("destination directory %s is not empty", opts.to)
+	}
+
+	// Without privileges, file ownership can't be set and device nodes can't be created;
+	// export everything else instead of failing.
+	rootless := unshare.IsRootless()
+	if rootless {
+		logrus.Warn("Not running as root, file ownership and device nodes are not preserved")
+	}
+	for i, layer := range layers {
+		logrus.Debugf("Applying layer %d/%d %s", i+1, len(layers), layer.Digest)
+		stream, err := openLayer(ctx, src, layer, opts.retryOpts)
+		if err != nil {
+			return err
+		}
+		_, err = archive.ApplyUncompressedLayer(opts.to, stream, &archive.TarOptions{
+			IgnoreChownErrors: rootless,
+			InUserNS:          rootless,
+		})
+		stream.Close()
+		if err != nil {
+			return i18n.Errorf("Error applying layer %s: %w", layer.Digest, err)
+		}
+	}
+	return nil
+}
+
+// exportTarFile writes the merged root filesystem to the opts.to tar file, removing it on failure.
+func (opts *exportOptions) exportTarFile(ctx context.Context, src types.ImageSource, layers []types.BlobInfo) (retErr error) {
+	f, err := os.Create(opts.to)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+		if retErr != nil {
+			os.Remove(opts.to)
+		}
+	}()
+	return opts.exportTar(ctx, src, layers, f)
+}
+
+// exportTar writes the merged root filesystem as a tar stream to w.
+// Directories are written before their contents, and hard links keep the contents their target had in the
+// layer of the link; this requires reading the layers twice, so they are first downloaded to temporary files.
+func (opts *exportOptions) exportTar(ctx context.Context, src types.ImageSource, layers []types.BlobInfo, w io.Writer) error {
+	dir, err := os.MkdirTemp(opts.global.tmpDir, "gopull-export")
+	if err != nil {
+		return err
+	}
+	removeDir := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			logrus.Warnf("Removing %s: %v", dir, err)
+		}
+	}
+	defer removeDir()
+	defer atInterrupt(removeDir)()
+
+	paths := make([]string, len(layers))
+	flattener := rootfs.NewFlattener()
+	for i, layer := range layers {
+		logrus.Debugf("Reading layer %d/%d %s", i+1, len(layers), layer.Digest)
+		paths[i] = filepath.Join(dir, fmt.Sprintf("layer-%d", i))
+		if err := downloadLayer(ctx, src, layer, paths[i], opts.retryOpts); err != nil {
+			return err
+		}
+		stream, err := openDownloadedLayer(layer, paths[i])
+		if err != nil {
+			return err
+		}
+		err = flattener.Layer(stream)
+		stream.Close()
+		if err != nil {
+			return i18n.Errorf("Error reading layer %s: %w", layer.Digest, err)
+		}
+	}
+
+	tw := tar.NewWriter(w)
+	if err := flattener.Write(tw, func(i int) (io.ReadCloser, error) {
+		return openDownloadedLayer(layers[i], paths[i])
+	}); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// downloadLayer writes the blob of layer from src to a new file at path.
+func downloadLayer(ctx context.Context, src types.ImageSource, layer types.BlobInfo, path string, retryOpts *retry.Options) (retErr error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	if err := retry.IfNecessary(ctx, func() error {
+		// Start again after a failed attempt.
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		blob, _, err := src.GetBlob(ctx, layer, none.NoCache)
+		if err != nil {
+			return err
+		}
+		defer blob.Close()
+		_, err = io.Copy(f, blob)
+		return err
+	}, retryOpts); err != nil {
+		return i18n.Errorf("Error reading layer %s: %w", layer.Digest, err)
+	}
+	return nil
+}
+
+// openDownloadedLayer returns the uncompressed tar stream of layer, downloaded to path by downloadLayer.
+// The caller must call .Close() on the returned stream.
+func openDownloadedLayer(layer types.BlobInfo, path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return decompressLayer(layer, f)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"gopull/pkgs/retry"
+)
+
+func TestExportTar(t *testing.T) {
+	src, layers := fakeLayers(t,
+		[]string{
+			"usr/", "usr/bin/", "usr/bin/tool=v1", "usr/bin/alias => usr/bin/tool", "usr/bin/alias2 => usr/bin/tool",
+			"etc/", "etc/removed=removed", "var/", "var/cache/", "var/cache/old=old",
+		},
+		[]string{"usr/bin/tool=v2", "usr/bin/current => usr/bin/tool", "etc/.wh.removed=", "var/cache/.wh..wh..opq="},
+		[]string{"opt/", "opt/app/", "opt/app/run=run", "usr/bin/new=new", "var/cache/new=new"},
+	)
+	opts := exportOptions{global: &globalOptions{tmpDir: t.TempDir()}, retryOpts: &retry.Options{}}
+	var buf bytes.Buffer
+	if err := opts.exportTar(context.Background(), src, layers, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []string
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			entries = append(entries, hdr.Name+"/")
+		case tar.TypeLink:
+			entries = append(entries, hdr.Name+" => "+hdr.Linkname)
+		default:
+			contents, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, hdr.Name+"="+string(contents))
+		}
+	}
+	// Directories come first, parents before their contents, even if only a lower layer defines them.
+	// The hard links in the bottom layer keep the contents their target had there.
+	expected := []string{
+		"etc/", "opt/", "opt/app/", "usr/", "usr/bin/", "var/", "var/cache/",
+		"usr/bin/alias=v1", "usr/bin/alias2 => usr/bin/alias",
+		"usr/bin/tool=v2", "usr/bin/current => usr/bin/tool",
+		"opt/app/run=run", "usr/bin/new=new", "var/cache/new=new",
+	}
+	if !slices.Equal(entries, expected) {
+		t.Errorf("exported %q, expected %q", entries, expected)
+	}
+}
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containers/common/pkg/report"
+	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/types"
+	"github.com/docker/go-units"
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/progress"
+	"gopull/pkgs/retry"
+)
+
+// historyCreatedByWidth is the width created_by is truncated to in the table output, unless --no-trunc is used.
+const historyCreatedByWidth = 60
+
+// historyEntry is one step of the image history, joined with the layer it created (if any).
+type historyEntry struct {
+	Created    *time.Time
+	CreatedBy  string
+	Comment    string
+	EmptyLayer bool
+	Layer      digest.Digest `json:",omitempty"`
+	Size       int64         // Compressed size of Layer; -1 if unknown, 0 for empty layers
+	SizeHuman  string
+}
+
+type historyOptions struct {
+	global     *globalOptions
+	image      *imageOptions
+	retryOpts  *retry.Options
+	format     string
+	noTrunc    bool // Do not truncate created_by in the table output
+	dockerfile bool // Output a reconstructed Dockerfile instead of the history
+}
+
+func historyCmd(global *globalOptions) *cobra.Command {
+	sharedFlags, sharedOpts := sharedImageFlags()
+	imageFlags, imageOpts := imageFlags(global, sharedOpts, nil, "", "")
+	retryFlags, retryOpts := retryFlags()
+	opts := historyOptions{
+		global:    global,
+		image:     imageOpts,
+		retryOpts: retryOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "history [command options] IMAGE-NAME",
+		Short: i18n.T("Show the build history of image IMAGE-NAME"),
+		Long: fmt.Sprintf(i18n.T(`Show how "IMAGE-NAME" was built, from the history recorded in its configuration,
+without pulling the image.
+
+"IMAGE-NAME" may be a registry reference, a "transport:details" name, or a path
+to a docker-archive or oci-archive file or an OCI layout directory.
+
+Supported transports:
+%s
+`), strings.Join(transports.ListNames(), ", ")),
+		RunE: commandAction(opts.run),
+		Example: `gopull history redis
+gopull history --no-trunc redis:7
+gopull history --dockerfile redis
+gopull history --format "{{.Created}} {{.SizeHuman}} {{.CreatedBy}}" redis.tar`,
+		ValidArgsFunction: autocompleteSupportedTransports,
+	}
+	adjustUsage(cmd)
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.format, "format", "f", "", i18n.T("Format the output to a Go template, or json"))
+	flags.BoolVar(&opts.noTrunc, "no-trunc", false, i18n.T("Do not truncate the created_by column"))
+	flags.BoolVar(&opts.dockerfile, "dockerfile", false, i18n.T("Output a best-effort reconstructed Dockerfile"))
+	flags.AddFlagSet(&sharedFlags)
+	flags.AddFlagSet(&imageFlags)
+	flags.AddFlagSet(&retryFlags)
+	return cmd
+}
+
+func (opts *historyOptions) run(args []string, stdout io.Writer) (retErr error) {
+	if len(args) != 1 {
+		return i18n.New("Exactly one argument expected")
+	}
+	if opts.dockerfile && opts.format != "" {
+		return i18n.New("--dockerfile does not support the format option")
+	}
+	ctx, cancel := opts.global.commandTimeoutContext()
+	defer cancel()
+
+	src, img, err := openSourceImage(ctx, opts.image, args[0], opts.retryOpts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing image", err)
+		}
+	}()
+
+	var config *v1.Image
+	if err := retry.IfNecessary(ctx, func() error {
+		config, err = img.OCIConfig(ctx)
+		return err
+	}, opts.retryOpts); err != nil {
+		return i18n.Errorf("Error reading OCI-formatted configuration data: %w", err)
+	}
+
+	entries := newHistoryEntries(config.History, img.LayerInfos())
+	if opts.dockerfile {
+		return writeDockerfile(stdout, entries)
+	}
+	// Like (docker history), list the most recent step first.
+	slices.Reverse(entries)
+	return opts.writeOutput(stdout, entries)
+}
+
+// newHistoryEntries lines up history with the layers of the image; every history item which is not
+// marked as an empty layer corresponds to the next layer.
+func newHistoryEntries(history []v1.History, layers []types.BlobInfo) []historyEntry {
+	nonEmpty := 0
+	for _, h := range history {
+		if !h.EmptyLayer {
+			nonEmpty++
+		}
+	}
+	aligned := nonEmpty == len(layers)
+	if !aligned {
+		logrus.Warnf("Image history lists %d layers, but the image has %d; layer sizes are not shown", nonEmpty, len(layers))
+	}
+
+	entries := make([]historyEntry, 0, len(history))
+	layerIndex := 0
+	for _, h := range history {
+		entry := historyEntry{
+			Created:    h.Created,
+			CreatedBy:  h.CreatedBy,
+			Comment:    h.Comment,
+			EmptyLayer: h.EmptyLayer,
+		}
+		switch {
+		case h.EmptyLayer:
+			entry.Size = 0
+		case aligned:
+			entry.Layer = layers[layerIndex].Digest
+			entry.Size = layers[layerIndex].Size
+			layerIndex++
+		default:
+			entry.Size = -1
+		}
+		entry.SizeHuman = progress.HumanSize(entry.Size)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// writeOutput writes entries depending on opts.format to stdout
+func (opts *historyOptions) writeOutput(stdout io.Writer, entries []historyEntry) error {
+	switch {
+	case opts.format == "":
+		return opts.writeTable(stdout, entries)
+	case report.IsJSON(opts.format):
+		out, err := json.MarshalIndent(entries, "", "    ")
+		if err == nil {
+			fmt.Fprintf(stdout, "%s\n", string(out))
+		}
+		return err
+	}
+
+	rpt, err := report.New(stdout, "gopull history").Parse(report.OriginUser, opts.format)
+	if err != nil {
+		return err
+	}
+	defer rpt.Flush()
+	data := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		data = append(data, entry)
+	}
+	return rpt.Execute(data)
+}
+
+// writeTable writes entries as a (docker history)-like table.
+func (opts *historyOptions) writeTable(stdout io.Writer, entries []historyEntry) error {
+	tw := tabwriter.NewWriter(stdout, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("CREATED\tCREATED BY\tSIZE\tCOMMENT"))
+	for _, entry := range entries {
+		created := "<missing>"
+		if entry.Created != nil {
+			created = fmt.Sprintf(i18n.T("%s ago"), units.HumanDuration(time.Since(*entry.Created)))
+		}
+		createdBy := strings.Join(strings.Fields(entry.CreatedBy), " ")
+		if r := []rune(createdBy); !opts.noTrunc && len(r) > historyCreatedByWidth {
+			createdBy = string(r[:historyCreatedByWidth-3]) + "..."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", created, createdBy, entry.SizeHuman, entry.Comment)
+	}
+	return tw.Flush()
+}
+
+var (
+	// historyNopPrefix matches the prefix used by the classic builder for steps which do not run a command.
+	historyNopPrefix = regexp.MustCompile(`^/bin/sh -c #\(nop\)\s*`)
+	// historyShellPrefix matches the shell the classic builder runs RUN steps with, with optional build arguments.
+	historyShellPrefix = regexp.MustCompile(`^(\|\d+ (\S+=\S*\s+)*)?/bin/sh -c\s+`)
+	// historyBuildArgsPrefix matches the build arguments BuildKit records for RUN steps.
+	historyBuildArgsPrefix = regexp.MustCompile(`^\|(\d+)\s+`)
+	// historyExposeMap matches the Go map syntax BuildKit records for EXPOSE.
+	historyExposeMap = regexp.MustCompile(`^map\[(.*)\]$`)
+)
+
+// dockerfileInstruction converts a created_by history value into a best-effort Dockerfile instruction.
+func dockerfileInstruction(createdBy string) string {
+	s := strings.TrimSpace(createdBy)
+	s = strings.TrimSpace(strings.TrimSuffix(s, "# buildkit"))
+	if s == "" {
+		return ""
+	}
+
+	if historyNopPrefix.MatchString(s) {
+		s = historyNopPrefix.ReplaceAllString(s, "")
+	} else if historyShellPrefix.MatchString(s) {
+		return "RUN " + historyShellPrefix.ReplaceAllString(s, "")
+	}
+
+	instruction, rest, _ := strings.Cut(s, " ")
+	switch instruction {
+	case "RUN":
+		rest = stripBuildArgs(rest)
+		if historyShellPrefix.MatchString(rest) {
+			rest = historyShellPrefix.ReplaceAllString(rest, "")
+		}
+		return "RUN " + rest
+	case "ADD", "COPY":
+		// The classic builder records "ADD file:<digest> in <dest>".
+		if src, dest, ok := strings.Cut(rest, " in "); ok {
+			rest = strings.TrimSpace(src) + " " + strings.TrimSpace(dest)
+		}
+		return instruction + " " + strings.TrimSpace(rest)
+	case "EXPOSE":
+		if m := historyExposeMap.FindStringSubmatch(rest); m != nil {
+			rest = strings.ReplaceAll(m[1], ":{}", "")
+		}
+		return "EXPOSE " + rest
+	case "CMD", "ENTRYPOINT", "ENV", "LABEL", "WORKDIR", "USER", "VOLUME", "ARG", "STOPSIGNAL", "HEALTHCHECK", "SHELL", "ONBUILD", "MAINTAINER":
+		return s
+	}
+	return "# " + s
+}
+
+// stripBuildArgs removes the "|N NAME=value …" build arguments BuildKit prepends to RUN commands.
+func stripBuildArgs(s string) string {
+	m := historyBuildArgsPrefix.FindStringSubmatch(s)
+	if m == nil {
+		return s
+	}
+	var n int
+	if _, err := fmt.Sscanf(m[1], "%d", &n); err != nil {
+		return s
+	}
+	fields := strings.SplitN(s, " ", n+2)
+	if len(fields) < n+2 {
+		return s
+	}
+	return fields[n+1]
+}
+
+// writeDockerfile writes a Dockerfile reconstructed from entries, which must be in build order.
+func writeDockerfile(stdout io.Writer, entries []historyEntry) error {
+	fmt.Fprintln(stdout, i18n.T("# Reconstructed from the image history; the build context and base image are not recoverable."))
+	fmt.Fprintln(stdout, "FROM scratch")
+	for _, entry := range entries {
+		instruction := dockerfileInstruction(entry.CreatedBy)
+		if instruction == "" {
+			continue
+		}
+		if _, err := fmt.Fprintln(stdout, instruction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDockerfileInstruction(t *testing.T) {
+	for _, c := range []struct {
+		createdBy, expected string
+	}{
+		{"", ""},
+		// Classic builder
+		{`/bin/sh -c #(nop)  CMD ["sh"]`, `CMD ["sh"]`},
+		{`/bin/sh -c #(nop) ADD file:0123abcd in / `, "ADD file:0123abcd /"},
+		{`/bin/sh -c #(nop) COPY dir:4567ef in /usr/src/app `, "COPY dir:4567ef /usr/src/app"},
+		{`/bin/sh -c #(nop)  EXPOSE 80/tcp`, "EXPOSE 80/tcp"},
+		{`/bin/sh -c #(nop) WORKDIR /app`, "WORKDIR /app"},
+		{"/bin/sh -c apt-get update && apt-get install -y curl", "RUN apt-get update && apt-get install -y curl"},
+		{"|2 VERSION=1.0 TARGET=prod /bin/sh -c make", "RUN make"},
+		// BuildKit
+		{"RUN /bin/sh -c make install # buildkit", "RUN make install"},
+		{"RUN |2 VERSION=1.0 TARGET=prod /bin/sh -c make install # buildkit", "RUN make install"},
+		{"RUN |1 EMPTY= /bin/sh -c echo hi # buildkit", "RUN echo hi"},
+		{`RUN |1 VERSION=1.0 make "$VERSION" # buildkit`, `RUN make "$VERSION"`},
+		{"ADD file:0123abcd in /dst", "ADD file:0123abcd /dst"},
+		{"COPY app /app # buildkit", "COPY app /app"},
+		{"EXPOSE map[80/tcp:{}]", "EXPOSE 80/tcp"},
+		{"EXPOSE map[443/tcp:{} 80/tcp:{}]", "EXPOSE 443/tcp 80/tcp"},
+		{"ENV PATH=/usr/local/bin:/usr/bin", "ENV PATH=/usr/local/bin:/usr/bin"},
+		// Anything else is kept as a comment.
+		{"created by a custom tool", "# created by a custom tool"},
+	} {
+		if res := dockerfileInstruction(c.createdBy); res != c.expected {
+			t.Errorf("%q: %q, expected %q", c.createdBy, res, c.expected)
+		}
+	}
+}
+
+func TestStripBuildArgs(t *testing.T) {
+	for _, c := range []struct {
+		input, expected string
+	}{
+		{"make", "make"},
+		{"|1 A=1 make", "make"},
+		{"|2 A=1 B= make install", "make install"},
+		{"|0 make", "make"},
+		{"|3 A=1 make", "|3 A=1 make"}, // Fewer arguments than announced
+		{"|x A=1 make", "|x A=1 make"},
+	} {
+		if res := stripBuildArgs(c.input); res != c.expected {
+			t.Errorf("%q: %q, expected %q", c.input, res, c.expected)
+		}
+	}
+}
+
+func TestWriteTableTruncatesRunes(t *testing.T) {
+	createdBy := "RUN echo " + strings.Repeat("é", historyCreatedByWidth)
+	var buf bytes.Buffer
+	if err := (&historyOptions{}).writeTable(&buf, []historyEntry{{CreatedBy: createdBy}}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !utf8.ValidString(out) {
+		t.Errorf("invalid UTF-8: %q", out)
+	}
+	expected := string([]rune(createdBy)[:historyCreatedByWidth-3]) + "..."
+	if !strings.Contains(out, expected) {
+		t.Errorf("%q does not contain %q", out, expected)
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,23 +9,39 @@ import (
 	"strings"
 
 	"github.com/containers/common/pkg/report"
-	"github.com/containers/common/pkg/retry"
 	"github.com/containers/image/v5/docker"
 	"github.com/containers/image/v5/image"
 	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/signature"
 	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/transports/alltransports"
 	"github.com/containers/image/v5/types"
 	"github.com/containers/skopeo/cmd/skopeo/inspect"
 	"github.com/docker/distribution/registry/api/errcode"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
 )
 
+// inspectOutput extends the (skopeo inspect) output with size information.
+type inspectOutput struct {
+	inspect.Output
+	CompressedSize                 int64
+	CompressedSizeHuman            string
+	EstimatedUncompressedSize      int64
+	EstimatedUncompressedSizeHuman string
+	LayerSizes                     []layerSize
+	signatureReport
+}
+
 type inspectOptions struct {
 	global        *globalOptions
 	image         *imageOptions
 	retryOpts     *retry.Options
+	verify        *verifyOptions
 	format        string
 	raw           bool // Output the raw manifest instead of parsing information about the image
 	config        bool // Output the raw config blob instead of parsing information about the image
@@ -35,35 +52,50 @@ func inspectCmd(global *globalOptions) *cobra.Command {
 	sharedFlags, sharedOpts := sharedImageFlags()
 	imageFlags, imageOpts := imageFlags(global, sharedOpts, nil, "", "")
 	retryFlags, retryOpts := retryFlags()
+	verifyFlags, verifyOpts := verifyFlags()
 	opts := inspectOptions{
 		global:    global,
 		image:     imageOpts,
 		retryOpts: retryOpts,
+		verify:    verifyOpts,
 	}
 	cmd := &cobra.Command{
 		Use:   "inspect [command options] IMAGE-NAME",
-		Short: "Inspect image IMAGE-NAME",
-		Long: fmt.Sprintf(`Return low-level information about "IMAGE-NAME" in a registry/transport
+		Short: i18n.T("Inspect image IMAGE-NAME"),
+		Long: fmt.Sprintf(i18n.T(`Return low-level information about "IMAGE-NAME" in a registry/transport
+
+"IMAGE-NAME" may be a registry reference, a "transport:details" name, or a path
+to a docker-archive or oci-archive file or an OCI layout directory.
+
+With --verify-key or --verify-gpg, the signatures of the image are read and verified,
+the output includes their number, and the command fails if the image is rejected.
+
 Supported transports:
 %s
 
 See skopeo(1) section "IMAGE NAMES" for the expected format
-`, strings.Join(transports.ListNames(), ", ")),
+`), strings.Join(transports.ListNames(), ", ")),
 		RunE: commandAction(opts.run),
 		Example: `gopull inspect registry.fedoraproject.org/fedora
 gopull inspect --config alpine
-gopull inspect --format "Name: {{.Name}} Digest: {{.Digest}}" docker://registry.access.redhat.com/ubi8`,
+gopull inspect --format "Name: {{.Name}} Digest: {{.Digest}}" docker://registry.access.redhat.com/ubi8
+gopull inspect --format "{{.CompressedSizeHuman}} (~{{.EstimatedUncompressedSizeHuman}} on disk)" redis
+gopull inspect --verify-key cosign.pub --format "{{.SignatureStatus}}" registry.example.com/app:1.0
+gopull inspect redis.tar
+gopull inspect oci-archive:redis.tar
+gopull inspect docker-daemon:redis:latest`,
 		ValidArgsFunction: autocompleteSupportedTransports,
 	}
 	adjustUsage(cmd)
 	flags := cmd.Flags()
-	flags.BoolVar(&opts.raw, "raw", false, "output raw manifest or configuration")
-	flags.BoolVar(&opts.config, "config", false, "output configuration")
-	flags.StringVarP(&opts.format, "format", "f", "", "Format the output to a Go template")
-	flags.BoolVarP(&opts.doNotListTags, "no-tags", "n", false, "Do not list the available tags from the repository in the output")
+	flags.BoolVar(&opts.raw, "raw", false, i18n.T("output raw manifest or configuration"))
+	flags.BoolVar(&opts.config, "config", false, i18n.T("output configuration"))
+	flags.StringVarP(&opts.format, "format", "f", "", i18n.T("Format the output to a Go template"))
+	flags.BoolVarP(&opts.doNotListTags, "no-tags", "n", false, i18n.T("Do not list the available tags from the repository in the output"))
 	flags.AddFlagSet(&sharedFlags)
 	flags.AddFlagSet(&imageFlags)
 	flags.AddFlagSet(&retryFlags)
+	flags.AddFlagSet(&verifyFlags)
 	return cmd
 }
 
@@ -77,24 +109,60 @@ func (opts *inspectOptions) run(args []string, stdout io.Writer) (retErr error)
 	defer cancel()
 
 	if len(args) != 1 {
-		return errors.New("Exactly one argument expected")
+		return i18n.New("Exactly one argument expected")
 	}
+	defer func() {
+		if interrupted(ctx, retErr) {
+			retErr = i18n.Errorf("%w: %s was not inspected, nothing was written", context.Cause(ctx), args[0])
+		}
+	}()
 	if opts.raw && opts.format != "" {
-		return errors.New("raw output does not support format option")
+		return i18n.New("raw output does not support format option")
 	}
-	imageName := "docker://" + args[0]
-
-	sys, err := opts.image.newSystemContext()
+	arg, err := opts.global.expandAlias(args[0])
+	if err != nil {
+		return err
+	}
+	imageName, err := sourceImageName(arg)
+	if err != nil {
+		return err
+	}
+	imageNames, err := opts.global.resolveSourceImageName(imageName)
 	if err != nil {
 		return err
 	}
 
-	if err := retry.IfNecessary(ctx, func() error {
-		src, err = parseImageSource(ctx, opts.image, imageName)
+	var sys *types.SystemContext
+	cleanup := func() {}
+	if err := imageNames.try(func(imageName string) error {
+		ref, err := alltransports.ParseImageName(imageName)
+		if err != nil {
+			return invalidReferenceError{i18n.Errorf("Error parsing image name %q: %w", imageName, err)}
+		}
+		ref, err = opts.global.mirrorReference(ref)
+		if err != nil {
+			return err
+		}
+		sys, err = opts.image.newSystemContextFor(ref)
+		if err != nil {
+			return err
+		}
+		cleanup, err = opts.verify.enableSigstoreAttachments(sys, ref)
+		if err != nil {
+			return err
+		}
+		if err := retry.IfNecessary(ctx, func() error {
+			src, err = ref.NewImageSource(ctx, sys)
+			return err
+		}, opts.retryOpts); err != nil {
+			cleanup()
+			return i18n.Errorf("Error parsing image name %q: %w", imageName, err)
+		}
+		return nil
+	}); err != nil {
 		return err
-	}, opts.retryOpts); err != nil {
-		return fmt.Errorf("Error parsing image name %q: %w", imageName, err)
 	}
+	defer cleanup()
 
 	defer func() {
 		if err := src.Close(); err != nil {
@@ -106,13 +174,13 @@ func (opts *inspectOptions) run(args []string, stdout io.Writer) (retErr error)
 		rawManifest, _, err = src.GetManifest(ctx, nil)
 		return err
 	}, opts.retryOpts); err != nil {
-		return fmt.Errorf("Error retrieving manifest for image: %w", err)
+		return i18n.Errorf("Error retrieving manifest for image: %w", err)
 	}
 
 	if opts.raw && !opts.config {
 		_, err := stdout.Write(rawManifest)
 		if err != nil {
-			return fmt.Errorf("Error writing manifest to standard output: %w", err)
+			return i18n.Errorf("Error writing manifest to standard output: %w", err)
 		}
 
 		return nil
@@ -120,7 +188,7 @@ func (opts *inspectOptions) run(args []string, stdout io.Writer) (retErr error)
 
 	img, err := image.FromUnparsedImage(ctx, sys, image.UnparsedInstance(src, nil))
 	if err != nil {
-		return fmt.Errorf("Error parsing manifest for image: %w", err)
+		return i18n.Errorf("Error parsing manifest for image: %w", err)
 	}
 
 	if opts.config && opts.raw {
@@ -129,11 +197,11 @@ func (opts *inspectOptions) run(args []string, stdout io.Writer) (retErr error)
 			configBlob, err = img.ConfigBlob(ctx)
 			return err
 		}, opts.retryOpts); err != nil {
-			return fmt.Errorf("Error reading configuration blob: %w", err)
+			return i18n.Errorf("Error reading configuration blob: %w", err)
 		}
 		_, err = stdout.Write(configBlob)
 		if err != nil {
-			return fmt.Errorf("Error writing configuration blob to standard output: %w", err)
+			return i18n.Errorf("Error writing configuration blob to standard output: %w", err)
 		}
 		return nil
 	} else if opts.config {
@@ -142,10 +210,10 @@ func (opts *inspectOptions) run(args []string, stdout io.Writer) (retErr error)
 			config, err = img.OCIConfig(ctx)
 			return err
 		}, opts.retryOpts); err != nil {
-			return fmt.Errorf("Error reading OCI-formatted configuration data: %w", err)
+			return i18n.Errorf("Error reading OCI-formatted configuration data: %w", err)
 		}
 		if err := opts.writeOutput(stdout, config); err != nil {
-			return fmt.Errorf("Error writing OCI-formatted configuration data to standard output: %w", err)
+			return i18n.Errorf("Error writing OCI-formatted configuration data to standard output: %w", err)
 		}
 		return nil
 	}
@@ -157,29 +225,41 @@ func (opts *inspectOptions) run(args []string, stdout io.Writer) (retErr error)
 		return err
 	}
 
-	outputData := inspect.Output{
-		Name: "", // Set below if DockerReference() is known
-		Tag:  imgInspect.Tag,
-		// Digest is set below.
-		RepoTags:      []string{}, // Possibly overridden for docker.Transport.
-		Created:       imgInspect.Created,
-		DockerVersion: imgInspect.DockerVersion,
-		Labels:        imgInspect.Labels,
-		Architecture:  imgInspect.Architecture,
-		Os:            imgInspect.Os,
-		Layers:        imgInspect.Layers,
-		LayersData:    imgInspect.LayersData,
-		Env:           imgInspect.Env,
+	size := newImageSize(img.LayerInfos())
+	outputData := inspectOutput{
+		Output: inspect.Output{
+			Name: "", // Set below if DockerReference() is known
+			Tag:  imgInspect.Tag,
+			// Digest is set below.
+			RepoTags:      []string{}, // Possibly overridden for docker.Transport.
+			Created:       imgInspect.Created,
+			DockerVersion: imgInspect.DockerVersion,
+			Labels:        imgInspect.Labels,
+			Architecture:  imgInspect.Architecture,
+			Os:            imgInspect.Os,
+			Layers:        imgInspect.Layers,
+			LayersData:    imgInspect.LayersData,
+			Env:           imgInspect.Env,
+		},
+		CompressedSize:                 size.CompressedSize,
+		CompressedSizeHuman:            size.CompressedSizeHuman,
+		EstimatedUncompressedSize:      size.EstimatedUncompressedSize,
+		EstimatedUncompressedSizeHuman: size.EstimatedUncompressedSizeHuman,
+		LayerSizes:                     size.LayerSizes,
+	}
+	outputData.signatureReport, err = opts.verify.checkSignatures(ctx, opts.global, src, opts.retryOpts)
+	if err != nil {
+		return err
 	}
 	outputData.Digest, err = manifest.Digest(rawManifest)
 	if err != nil {
-		return fmt.Errorf("Error computing manifest digest: %w", err)
+		return i18n.Errorf("Error computing manifest digest: %w", err)
 	}
 	if dockerRef := img.Reference().DockerReference(); dockerRef != nil {
 		outputData.Name = dockerRef.Name()
 	}
 	if !opts.doNotListTags && img.Reference().Transport() == docker.Transport {
-		sys, err := opts.image.newSystemContext()
+		sys, err := opts.image.newSystemContextFor(img.Reference())
 		if err != nil {
 			return err
 		}
@@ -205,12 +285,18 @@ func (opts *inspectOptions) run(args []string, stdout io.Writer) (retErr error)
 				}
 			}
 			if fatalFailure {
-				return fmt.Errorf("Error determining repository tags: %w", err)
+				return i18n.Errorf("Error determining repository tags: %w", err)
 			}
 			logrus.Warnf("Registry disallows tag list retrieval; skipping")
 		}
 	}
-	return opts.writeOutput(stdout, outputData)
+	if err := opts.writeOutput(stdout, outputData); err != nil {
+		return err
+	}
+	if outputData.SignatureStatus == "rejected" {
+		return i18n.Errorf("Signature verification failed: %w", signature.PolicyRequirementError(outputData.SignatureError))
+	}
+	return nil
 }
 
 // writeOutput writes data depending on opts.format to stdout
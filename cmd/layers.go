@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/containers/image/v5/pkg/blobinfocache/none"
+	"github.com/containers/image/v5/pkg/compression"
+	"github.com/containers/image/v5/types"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
+	"gopull/pkgs/rootfs"
+)
+
+// layerReadCloser closes both the decompressed stream and the underlying blob.
+type layerReadCloser struct {
+	io.ReadCloser
+	blob io.ReadCloser
+}
+
+func (r *layerReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	if blobErr := r.blob.Close(); err == nil {
+		err = blobErr
+	}
+	return err
+}
+
+// openLayer returns the uncompressed tar stream of layer, as returned by types.Image.LayerInfos, from src.
+// The caller must call .Close() on the returned stream.
+func openLayer(ctx context.Context, src types.ImageSource, layer types.BlobInfo, retryOpts *retry.Options) (io.ReadCloser, error) {
+	var blob io.ReadCloser
+	if err := retry.IfNecessary(ctx, func() error {
+		var err error
+		blob, _, err = src.GetBlob(ctx, layer, none.NoCache)
+		return err
+	}, retryOpts); err != nil {
+		return nil, i18n.Errorf("Error reading layer %s: %w", layer.Digest, err)
+	}
+	return decompressLayer(layer, blob)
+}
+
+// decompressLayer returns the uncompressed tar stream of layer, read from blob, which is closed
+// together with the returned stream.
+func decompressLayer(layer types.BlobInfo, blob io.ReadCloser) (io.ReadCloser, error) {
+	if strings.HasSuffix(layer.MediaType, "+encrypted") {
+		blob.Close()
+		return nil, i18n.Errorf("layer %s is encrypted", layer.Digest)
+	}
+	stream, _, err := compression.AutoDecompress(blob)
+	if err != nil {
+		blob.Close()
+		return nil, i18n.Errorf("Error decompressing layer %s: %w", layer.Digest, err)
+	}
+	return &layerReadCloser{ReadCloser: stream, blob: blob}, nil
+}
+
+// maxSymlinkHops limits how many symbolic links readImageFile follows, like ELOOP in the kernel.
+const maxSymlinkHops = 40
+
+// fileNotFoundError is returned by readImageFile if the path does not exist in the image.
+type fileNotFoundError struct {
+	path string
+}
+
+// Error is translated when called, so that the language selected by the command line is used.
+func (e fileNotFoundError) Error() string {
+	return fmt.Sprintf(i18n.T("%s: no such file in image"), e.path)
+}
+
+// readImageFile finds the regular file at filePath in the merged filesystem of an image and calls fn with
+// its header and contents. Layers are read from the top down, and reading stops at the first layer
+// which contains the file, so lower layers are never downloaded. Symbolic links, both of the file and
+// of its parent directories, are followed within the image.
+func readImageFile(ctx context.Context, src types.ImageSource, layers []types.BlobInfo, retryOpts *retry.Options,
+	filePath string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	target := rootfs.CleanPath(filePath)
+	// Hard links refer to a file in the same or a lower layer, symbolic links are resolved from the top.
+	candidates := layers
+	for hops := 0; hops <= maxSymlinkHops; hops++ {
+		var (
+			found       bool
+			next        string // Path to continue the search with
+			hardlinkTop int    // For hard links, the number of layers which may contain the link target
+		)
+		merger := rootfs.NewMerger()
+		err := walkLayersTopDown(ctx, src, candidates, retryOpts, merger, func(entry *rootfs.Entry, r io.Reader) error {
+			hdr := entry.Header
+			if entry.Shadowed {
+				return nil
+			}
+			if hdr.Name == target {
+				switch hdr.Typeflag {
+				case tar.TypeReg:
+					found = true
+					if err := fn(hdr, r); err != nil {
+						return err
+					}
+				case tar.TypeSymlink:
+					next = resolveSymlink(hdr.Name, hdr.Linkname)
+				case tar.TypeLink:
+					next = hdr.Linkname
+					hardlinkTop = entry.Layer + 1
+				case tar.TypeDir:
+					return i18n.Errorf("%s is a directory", filePath)
+				default:
+					return i18n.Errorf("%s is not a regular file", filePath)
+				}
+				return rootfs.ErrStop
+			}
+			if hdr.Typeflag == tar.TypeSymlink && strings.HasPrefix(target, hdr.Name+"/") {
+				next = path.Join(resolveSymlink(hdr.Name, hdr.Linkname), strings.TrimPrefix(target, hdr.Name+"/"))
+				return rootfs.ErrStop
+			}
+			return nil
+		}, func() bool {
+			return found || next != "" || merger.Deleted(target)
+		})
+		if err != nil {
+			return err
+		}
+		switch {
+		case found:
+			return nil
+		case next == "":
+			return fileNotFoundError{path: filePath}
+		}
+		target = next
+		candidates = layers
+		if hardlinkTop != 0 {
+			candidates = layers[:hardlinkTop]
+		}
+	}
+	return i18n.Errorf("%s: too many levels of symbolic links", filePath)
+}
+
+// resolveSymlink returns the path, relative to the image root, a symbolic link at name with linkname points to.
+func resolveSymlink(name, linkname string) string {
+	if path.IsAbs(linkname) {
+		return rootfs.CleanPath(linkname)
+	}
+	return rootfs.CleanPath(path.Join(path.Dir(name), linkname))
+}
+
+// walkLayersTopDown feeds the layers of an image, as returned by types.Image.LayerInfos, to merger
+// starting with the topmost one, calling fn for every entry.
+// After each layer, done is called (if not nil); if it returns true, no further layers are read.
+func walkLayersTopDown(ctx context.Context, src types.ImageSource, layers []types.BlobInfo, retryOpts *retry.Options,
+	merger *rootfs.Merger, fn rootfs.WalkFunc, done func() bool) error {
+	layers = slices.Clone(layers)
+	slices.Reverse(layers)
+	var fnErr error // Errors from fn are returned as is, only errors reading the layer are annotated.
+	walkFn := func(entry *rootfs.Entry, r io.Reader) error {
+		fnErr = fn(entry, r)
+		return fnErr
+	}
+	for i, layer := range layers {
+		stream, err := openLayer(ctx, src, layer, retryOpts)
+		if err != nil {
+			return err
+		}
+		err = merger.Layer(len(layers)-1-i, stream, walkFn)
+		stream.Close()
+		if err != nil {
+			if err == fnErr {
+				return err
+			}
+			return i18n.Errorf("Error reading layer %s: %w", layer.Digest, err)
+		}
+		if done != nil && done() {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/containers/image/v5/types"
+	"github.com/opencontainers/go-digest"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
+)
+
+// fakeLayerSource is an ImageSource serving uncompressed layers, which records the layers read.
+type fakeLayerSource struct {
+	types.ImageSource
+	blobs map[digest.Digest][]byte
+	read  []digest.Digest
+}
+
+func (src *fakeLayerSource) GetBlob(_ context.Context, info types.BlobInfo, _ types.BlobInfoCache) (io.ReadCloser, int64, error) {
+	data, ok := src.blobs[info.Digest]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown blob %s", info.Digest)
+	}
+	src.read = append(src.read, info.Digest)
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+// fakeLayers returns a source serving layers, listed from the bottom up, and their BlobInfos.
+// Each entry of a layer is "DIR/", "NAME -> SYMLINK TARGET", "NAME => HARD LINK TARGET" or "NAME=CONTENTS".
+func fakeLayers(t *testing.T, layers ...[]string) (*fakeLayerSource, []types.BlobInfo) {
+	t.Helper()
+	src := &fakeLayerSource{blobs: map[digest.Digest][]byte{}}
+	var infos []types.BlobInfo
+	for _, entries := range layers {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, entry := range entries {
+			hdr := &tar.Header{Name: entry, Typeflag: tar.TypeDir, Mode: 0o755}
+			var contents string
+			if name, linkname, ok := strings.Cut(entry, " -> "); ok {
+				hdr = &tar.Header{Name: name, Typeflag: tar.TypeSymlink, Linkname: linkname, Mode: 0o777}
+			} else if name, linkname, ok := strings.Cut(entry, " => "); ok {
+				hdr = &tar.Header{Name: name, Typeflag: tar.TypeLink, Linkname: linkname, Mode: 0o644}
+			} else if name, value, ok := strings.Cut(entry, "="); ok {
+				contents = value
+				hdr = &tar.Header{Name: name, Typeflag: tar.TypeReg, Size: int64(len(value)), Mode: 0o644}
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte(contents)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		d := digest.FromBytes(buf.Bytes())
+		src.blobs[d] = buf.Bytes()
+		infos = append(infos, types.BlobInfo{Digest: d, Size: int64(buf.Len())})
+	}
+	return src, infos
+}
+
+func TestReadImageFile(t *testing.T) {
+	layers := [][]string{
+		{
+			"etc/", "etc/os-release=debian", "etc/hostname=base", "usr/", "usr/lib/", "usr/lib/os-release=usr-lib",
+			"opt/", "opt/real/", "opt/real/data=real-data", "var/", "var/old=old", "var/link => var/old",
+		},
+		{
+			"etc/", "etc/os-release -> ../usr/lib/os-release", "etc/hostname=top", "opt/", "opt/current -> real",
+			"etc/abs -> /etc/hostname", "etc/dangling -> missing", "loop/", "loop/a -> b", "loop/b -> a",
+			"var/", "var/old=replaced", "var/hard => var/old",
+		},
+	}
+	for _, c := range []struct {
+		path, expected string // expected is "ERROR: TEXT" if an error containing TEXT is expected
+	}{
+		{"etc/hostname", "top"},
+		{"/etc/hostname", "top"},
+		{"etc/os-release", "usr-lib"},     // Relative symbolic link
+		{"etc/abs", "top"},                // Absolute symbolic link
+		{"opt/current/data", "real-data"}, // Symbolic link of a parent directory, in a lower layer
+		{"var/hard", "replaced"},          // Hard link in the upper layer, to its own file
+		{"var/link", "old"},               // Hard link in the lower layer, to the file it shadows in the upper one
+		{"etc/missing", "ERROR: no such file"},
+		{"etc/dangling", "ERROR: no such file"},
+		{"etc", "ERROR: is a directory"},
+		{"loop/a", "ERROR: too many levels of symbolic links"},
+	} {
+		src, infos := fakeLayers(t, layers...)
+		var res string
+		err := readImageFile(context.Background(), src, infos, &retry.Options{}, c.path, func(hdr *tar.Header, r io.Reader) error {
+			data, err := io.ReadAll(r)
+			res = string(data)
+			return err
+		})
+		if err != nil {
+			res = "ERROR: " + err.Error()
+		}
+		if expectedErr, ok := strings.CutPrefix(c.expected, "ERROR: "); ok && strings.Contains(res, expectedErr) {
+			continue
+		}
+		if res != c.expected {
+			t.Errorf("%s: %q, expected %q", c.path, res, c.expected)
+		}
+	}
+}
+
+func TestReadImageFileStopsAtTopLayer(t *testing.T) {
+	src, infos := fakeLayers(t, []string{"a=lower"}, []string{"a=upper"})
+	if err := readImageFile(context.Background(), src, infos, &retry.Options{}, "a", func(*tar.Header, io.Reader) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(src.read, []digest.Digest{infos[1].Digest}) {
+		t.Errorf("read layers %v, expected only %s", src.read, infos[1].Digest)
+	}
+
+	// Errors of fn are returned as is.
+	fnErr := errors.New("fn failed")
+	if err := readImageFile(context.Background(), src, infos, &retry.Options{}, "a", func(*tar.Header, io.Reader) error {
+		return fnErr
+	}); err != fnErr {
+		t.Errorf("%v, expected %v", err, fnErr)
+	}
+}
+
+func TestReadImageFileNotFound(t *testing.T) {
+	src, infos := fakeLayers(t, []string{"a=lower"})
+	err := readImageFile(context.Background(), src, infos, &retry.Options{}, "b", func(*tar.Header, io.Reader) error {
+		return nil
+	})
+	var notFound fileNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("%v, expected a fileNotFoundError", err)
+	}
+
+	// The message is translated to the language selected after the error was created.
+	defer i18n.SetLanguage(i18n.Language())
+	i18n.SetLanguage(i18n.Chinese)
+	if expected := "b: 镜像中没有该文件"; err.Error() != expected {
+		t.Errorf("%q, expected %q", err.Error(), expected)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	commonFlag "github.com/containers/common/pkg/flag"
 	"github.com/containers/image/v5/types"
 	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
 )
 
 type loginOptions struct {
@@ -22,14 +24,14 @@ func loginCmd(global *globalOptions) *cobra.Command {
 	}
 	cmd := &cobra.Command{
 		Use:     "login [command options] REGISTRY",
-		Short:   "Login to a container registry",
-		Long:    "Login to a container registry on a specified server.",
+		Short:   i18n.T("Login to a container registry"),
+		Long:    i18n.T("Login to a container registry on a specified server."),
 		RunE:    commandAction(opts.run),
 		Example: `skopeo login docker.io`,
 	}
 	adjustUsage(cmd)
 	flags := cmd.Flags()
-	commonFlag.OptionalBoolFlag(flags, &opts.tlsVerify, "tls-verify", "require HTTPS and verify certificates when accessing the registry")
+	commonFlag.OptionalBoolFlag(flags, &opts.tlsVerify, "tls-verify", i18n.T("require HTTPS and verify certificates when accessing the registry"))
 	flags.AddFlagSet(auth.GetLoginFlags(&opts.loginOpts))
 	return cmd
 }
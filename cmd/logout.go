@@ -7,6 +7,8 @@ import (
 	commonFlag "github.com/containers/common/pkg/flag"
 	"github.com/containers/image/v5/types"
 	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
 )
 
 type logoutOptions struct {
@@ -21,14 +23,14 @@ func logoutCmd(global *globalOptions) *cobra.Command {
 	}
 	cmd := &cobra.Command{
 		Use:     "logout [command options] REGISTRY",
-		Short:   "Logout of a container registry",
-		Long:    "Logout of a container registry on a specified server.",
+		Short:   i18n.T("Logout of a container registry"),
+		Long:    i18n.T("Logout of a container registry on a specified server."),
 		RunE:    commandAction(opts.run),
 		Example: `gopull logout docker.io`,
 	}
 	adjustUsage(cmd)
 	flags := cmd.Flags()
-	commonFlag.OptionalBoolFlag(flags, &opts.tlsVerify, "tls-verify", "require HTTPS and verify certificates when accessing the registry")
+	commonFlag.OptionalBoolFlag(flags, &opts.tlsVerify, "tls-verify", i18n.T("require HTTPS and verify certificates when accessing the registry"))
 	flags.AddFlagSet(auth.GetLogoutFlags(&opts.logoutOpts))
 	return cmd
 }
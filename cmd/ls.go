@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/containers/common/pkg/report"
+	"github.com/containers/image/v5/transports"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/progress"
+	"gopull/pkgs/retry"
+	"gopull/pkgs/rootfs"
+)
+
+// lsEntry is a path in the merged filesystem of an image.
+type lsEntry struct {
+	Path         string
+	Mode         string
+	UID          int
+	GID          int
+	Size         int64
+	SizeHuman    string
+	LinkTarget   string        `json:",omitempty"` // For symbolic and hard links
+	AddedIn      int           // 1-based number of the lowest layer providing the path (after any deletion)
+	AddedLayer   digest.Digest // Digest of that layer
+	ChangedIn    int           // 1-based number of the topmost layer providing the path
+	ChangedLayer digest.Digest // Digest of that layer
+	hardlink     bool
+}
+
+type lsOptions struct {
+	global    *globalOptions
+	image     *imageOptions
+	retryOpts *retry.Options
+	recursive bool
+	format    string
+}
+
+func lsCmd(global *globalOptions) *cobra.Command {
+	sharedFlags, sharedOpts := sharedImageFlags()
+	imageFlags, imageOpts := imageFlags(global, sharedOpts, nil, "", "")
+	retryFlags, retryOpts := retryFlags()
+	opts := lsOptions{
+		global:    global,
+		image:     imageOpts,
+		retryOpts: retryOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "ls [command options] IMAGE-NAME [PATH]",
+		Short: i18n.T("List files in image IMAGE-NAME"),
+		Long: fmt.Sprintf(i18n.T(`List the contents of PATH (default "/") in the merged filesystem of "IMAGE-NAME",
+with the layer which added each entry, and the layer which last changed it.
+
+"IMAGE-NAME" may be a registry reference, a "transport:details" name, or a path
+to a docker-archive or oci-archive file or an OCI layout directory.
+
+Supported transports:
+%s
+`), strings.Join(transports.ListNames(), ", ")),
+		RunE: commandAction(opts.run),
+		Example: `gopull ls alpine /etc
+gopull ls -R redis.tar /usr/local/bin
+gopull ls -R --format "{{.Path}} {{.ChangedLayer}}" redis`,
+		ValidArgsFunction: autocompleteSupportedTransports,
+	}
+	adjustUsage(cmd)
+	flags := cmd.Flags()
+	flags.BoolVarP(&opts.recursive, "recursive", "R", false, i18n.T("List subdirectories recursively"))
+	flags.StringVarP(&opts.format, "format", "f", "", i18n.T("Format the output to a Go template, or json"))
+	flags.AddFlagSet(&sharedFlags)
+	flags.AddFlagSet(&imageFlags)
+	flags.AddFlagSet(&retryFlags)
+	return cmd
+}
+
+func (opts *lsOptions) run(args []string, stdout io.Writer) (retErr error) {
+	if len(args) != 1 && len(args) != 2 {
+		return errorShouldDisplayUsage{i18n.New("One or two arguments expected")}
+	}
+	dir := "/"
+	if len(args) == 2 {
+		dir = args[1]
+	}
+	ctx, cancel := opts.global.commandTimeoutContext()
+	defer cancel()
+
+	src, img, err := openSourceImage(ctx, opts.image, args[0], opts.retryOpts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing image", err)
+		}
+	}()
+
+	layers := img.LayerInfos()
+	headers := map[string]*tar.Header{}
+	entries := map[string]*lsEntry{}
+	merger := rootfs.NewMerger()
+	if err := walkLayersTopDown(ctx, src, layers, opts.retryOpts, merger, func(entry *rootfs.Entry, _ io.Reader) error {
+		hdr := entry.Header
+		layerNumber := entry.Layer + 1
+		if entry.Shadowed {
+			if e, ok := entries[hdr.Name]; ok {
+				e.AddedIn, e.AddedLayer = layerNumber, layers[entry.Layer].Digest
+			}
+			return nil
+		}
+		headers[hdr.Name] = hdr
+		entries[hdr.Name] = &lsEntry{
+			Path:         path.Join("/", hdr.Name),
+			Mode:         hdr.FileInfo().Mode().String(),
+			UID:          hdr.Uid,
+			GID:          hdr.Gid,
+			Size:         hdr.Size,
+			SizeHuman:    progress.HumanSize(hdr.Size),
+			LinkTarget:   hdr.Linkname,
+			AddedIn:      layerNumber,
+			AddedLayer:   layers[entry.Layer].Digest,
+			ChangedIn:    layerNumber,
+			ChangedLayer: layers[entry.Layer].Digest,
+			hardlink:     hdr.Typeflag == tar.TypeLink,
+		}
+		return nil
+	}, nil); err != nil {
+		return err
+	}
+
+	root, err := resolveMergedPath(headers, dir)
+	if err != nil {
+		return err
+	}
+	var selected []*lsEntry
+	if hdr, ok := headers[root]; ok && hdr.Typeflag != tar.TypeDir {
+		selected = append(selected, entries[root])
+	} else {
+		prefix := root + "/"
+		if root == "." {
+			prefix = ""
+		}
+		for name, e := range entries {
+			if name == "." || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if opts.recursive || !strings.Contains(strings.TrimPrefix(name, prefix), "/") {
+				selected = append(selected, e)
+			}
+		}
+		if len(selected) == 0 && root != "." {
+			if _, ok := headers[root]; !ok {
+				return fileNotFoundError{path: dir}
+			}
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Path < selected[j].Path })
+	return opts.writeOutput(stdout, selected)
+}
+
+// resolveMergedPath resolves symbolic links in p, using the headers of the merged filesystem,
+// and returns a cleaned path relative to the image root.
+func resolveMergedPath(headers map[string]*tar.Header, p string) (string, error) {
+	remaining := strings.Split(rootfs.CleanPath(p), "/")
+	resolved := "."
+	for hops := 0; len(remaining) > 0; {
+		component := remaining[0]
+		remaining = remaining[1:]
+		if component == "." {
+			continue
+		}
+		candidate := path.Join(resolved, component)
+		hdr, ok := headers[candidate]
+		if !ok || hdr.Typeflag != tar.TypeSymlink {
+			resolved = candidate
+			continue
+		}
+		hops++
+		if hops > maxSymlinkHops {
+			return "", i18n.Errorf("%s: too many levels of symbolic links", p)
+		}
+		target := resolveSymlink(candidate, hdr.Linkname)
+		remaining = append(strings.Split(target, "/"), remaining...)
+		resolved = "."
+	}
+	return resolved, nil
+}
+
+// writeOutput writes entries depending on opts.format to stdout
+func (opts *lsOptions) writeOutput(stdout io.Writer, entries []*lsEntry) error {
+	switch {
+	case opts.format == "":
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, i18n.T("MODE\tUID:GID\tSIZE\tADDED\tCHANGED\tPATH"))
+		for _, e := range entries {
+			name := e.Path
+			if e.hardlink {
+				name += fmt.Sprintf(i18n.T(" link to /%s"), e.LinkTarget)
+			} else if e.LinkTarget != "" {
+				name += " -> " + e.LinkTarget
+			}
+			fmt.Fprintf(tw, "%s\t%d:%d\t%s\t%d\t%d\t%s\n", e.Mode, e.UID, e.GID, e.SizeHuman, e.AddedIn, e.ChangedIn, name)
+		}
+		return tw.Flush()
+	case report.IsJSON(opts.format):
+		out, err := json.MarshalIndent(entries, "", "    ")
+		if err == nil {
+			fmt.Fprintf(stdout, "%s\n", string(out))
+		}
+		return err
+	}
+
+	rpt, err := report.New(stdout, "gopull ls").Parse(report.OriginUser, opts.format)
+	if err != nil {
+		return err
+	}
+	defer rpt.Flush()
+	data := make([]any, 0, len(entries))
+	for _, e := range entries {
+		data = append(data, e)
+	}
+	return rpt.Execute(data)
+}
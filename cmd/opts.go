@@ -2,17 +2,19 @@ package cmd
 
 import (
 	"context"
-	"errors"
 	"os"
 	"time"
 
 	commonFlag "github.com/containers/common/pkg/flag"
-	"github.com/containers/common/pkg/retry"
 	"github.com/containers/image/v5/directory"
 	"github.com/containers/image/v5/signature"
 	"github.com/containers/image/v5/types"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
+
+	"gopull/pkgs/config"
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
 )
 
 type globalOptions struct {
@@ -27,12 +29,25 @@ type globalOptions struct {
 	commandTimeout     time.Duration           // Timeout for the command execution
 	registriesConfPath string                  // Path to the "registries.conf" file
 	tmpDir             string                  // Path to use for big temporary files
+	errorFormat        string                  // How to report a failure, errorFormatText or errorFormatJSON
+	lang               string                  // Language of messages, overriding LANG
+	configPath         string                  // Path to the configuration file, if not the default one
+	profile            string                  // Profile of the configuration file to use
+	shortNameMode      string                  // How to resolve ambiguous short names, one of shortNameModes, or "" for registries.conf
+	noDockerHubDefault bool                    // Fail instead of using Docker Hub for short names registries.conf does not resolve
+	config             *config.Config          // The configuration file, or nil if there is none
+	configured         map[string]bool         // Options set from the configuration file
+	proxies            map[string]string       // Registries accessed, and their proxies in the configuration file; set by useProxy
+	ctx                context.Context         // Cancelled when gopull is interrupted; set by before
 }
 
 // commandTimeoutContext returns a context.Context and a cancellation callback based on opts.
 // The caller should usually "defer cancel()" immediately after calling this.
 func (opts *globalOptions) commandTimeoutContext() (context.Context, context.CancelFunc) {
-	ctx := context.Background()
+	ctx := opts.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	var cancel context.CancelFunc = func() {}
 	if opts.commandTimeout > 0 {
 		ctx, cancel = context.WithTimeout(ctx, opts.commandTimeout)
@@ -46,10 +61,8 @@ func (opts *globalOptions) getPolicyContext() (*signature.PolicyContext, error)
 	var err error
 	if opts.insecurePolicy {
 		policy = &signature.Policy{Default: []signature.PolicyRequirement{signature.NewPRInsecureAcceptAnything()}}
-	} else if opts.policyPath == "" {
-		policy, err = signature.DefaultPolicy(nil)
 	} else {
-		policy, err = signature.NewPolicyFromFile(opts.policyPath)
+		policy, _, err = opts.loadPolicy()
 	}
 	if err != nil {
 		return nil, err
@@ -69,6 +82,9 @@ func (opts *globalOptions) newSystemContext() *types.SystemContext {
 		BigFilesTemporaryDir:     opts.tmpDir,
 		DockerRegistryUserAgent:  defaultUserAgent,
 	}
+	if mode, ok := shortNameModes[opts.shortNameMode]; ok {
+		ctx.ShortNameMode = &mode
+	}
 	// DEPRECATED: We support this for backward compatibility, but override it if a per-image flag is provided.
 	if opts.tlsVerify.Present() {
 		ctx.DockerInsecureSkipTLSVerify = types.NewOptionalBool(!opts.tlsVerify.Value())
@@ -109,20 +125,20 @@ func (opts *imageOptions) newSystemContext() (*types.SystemContext, error) {
 		ctx.DockerInsecureSkipTLSVerify = types.NewOptionalBool(!opts.tlsVerify.Value())
 	}
 	if opts.credsOption.Present() && opts.noCreds {
-		return nil, errors.New("creds and no-creds cannot be specified at the same time")
+		return nil, i18n.New("creds and no-creds cannot be specified at the same time")
 	}
 	if opts.userName.Present() && opts.noCreds {
-		return nil, errors.New("username and no-creds cannot be specified at the same time")
+		return nil, i18n.New("username and no-creds cannot be specified at the same time")
 	}
 	if opts.credsOption.Present() && opts.userName.Present() {
-		return nil, errors.New("creds and username cannot be specified at the same time")
+		return nil, i18n.New("creds and username cannot be specified at the same time")
 	}
 	// if any of username or password is present, then both are expected to be present
 	if opts.userName.Present() != opts.password.Present() {
 		if opts.userName.Present() {
-			return nil, errors.New("password must be specified when username is specified")
+			return nil, i18n.New("password must be specified when username is specified")
 		}
-		return nil, errors.New("username must be specified when password is specified")
+		return nil, i18n.New("username must be specified when password is specified")
 	}
 	if opts.credsOption.Present() {
 		var err error
@@ -161,6 +177,7 @@ type dockerImageOptions struct {
 	dockerCertPath      string                     // A directory using Docker-like *.{crt,cert,key} files for connecting to a registry or a daemon
 	tlsVerify           commonFlag.OptionalBool    // Require HTTPS and verify certificates (for docker: and docker-daemon:)
 	noCreds             bool                       // Access the registry anonymously
+	flagPrefix          string                     // Prefix of the names of the options, e.g. "src-"
 }
 
 // sharedImageOptions collects CLI flags which are image-related, but do not change across images.
@@ -199,13 +216,17 @@ func (opts *imageDestOptions) warnAboutIneffectiveOptions(destTransport types.Im
 func sharedImageFlags() (pflag.FlagSet, *sharedImageOptions) {
 	opts := sharedImageOptions{}
 	fs := pflag.FlagSet{}
-	fs.StringVar(&opts.authFilePath, "authfile", os.Getenv("REGISTRY_AUTH_FILE"), "path of the authentication file. Default is ${XDG_RUNTIME_DIR}/containers/auth.json")
+	fs.StringVar(&opts.authFilePath, "authfile", os.Getenv("REGISTRY_AUTH_FILE"), i18n.T("path of the authentication file. Default is ${XDG_RUNTIME_DIR}/containers/auth.json"))
+	bindEnvironment(&fs)
 	return fs, &opts
 }
 
 func retryFlags() (pflag.FlagSet, *retry.Options) {
 	opts := retry.Options{}
 	fs := pflag.FlagSet{}
-	fs.IntVar(&opts.MaxRetry, "retry-times", 0, "the number of times to possibly retry")
+	fs.IntVar(&opts.MaxRetry, "retry-times", 0, i18n.T("the number of times to possibly retry"))
+	fs.DurationVar(&opts.Delay, "retry-delay", retry.DefaultDelay, i18n.T("delay before the first retry, doubled for every following retry, with a random jitter"))
+	fs.DurationVar(&opts.MaxDelay, "retry-max-delay", retry.DefaultMaxDelay, i18n.T("longest delay between retries"))
+	bindEnvironment(&fs)
 	return fs, &opts
 }
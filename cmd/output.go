@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/types"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
+)
+
+// outputFileOptions collects CLI flags for writing the destination of a copy to an archive file.
+type outputFileOptions struct {
+	force        bool // Replace an existing archive
+	skipExisting bool // Don't copy if the archive exists and contains the same image
+
+	path         string                                          // The archive to write, set when building the destination
+	newReference func(path string) (types.ImageReference, error) // Returns a reference to the archive format written, at path
+	removedTemp  bool                                            // The temporary file was removed after a failed copy
+}
+
+// outputFileFlags prepares a collection of CLI flags writing into outputFileOptions, and the managed outputFileOptions structure.
+func outputFileFlags() (pflag.FlagSet, *outputFileOptions) {
+	opts := outputFileOptions{}
+	fs := pflag.FlagSet{}
+	fs.BoolVar(&opts.force, "force", false, i18n.T("Replace the archive if it already exists"))
+	fs.BoolVar(&opts.skipExisting, "skip-existing", false, i18n.T("Don't download the image if the archive already exists and contains the same image"))
+	return fs, &opts
+}
+
+// begin prepares writing destRef, the destination of a copy from srcRef.
+// If destRef is the archive at opts.path, it is written to a temporary file in the same directory, which is renamed
+// to the archive when the copy succeeds, so that the archive is never incomplete; an existing archive is an error
+// unless --force.
+// It returns the reference to copy to, a function to call before every attempt of the copy, which empties
+// the temporary file again after a failed attempt, and a function to call with the result of the copy, which returns
+// the result of the whole operation. The returned reference is nil if the archive contains the image already
+// and --skip-existing.
+func (opts *outputFileOptions) begin(ctx context.Context, srcRef types.ImageReference, sourceCtx *types.SystemContext,
+	destRef types.ImageReference, retryOpts *retry.Options) (types.ImageReference, func() error, func(error) error, error) {
+	path := opts.path
+	if path == "" {
+		return destRef, func() error { return nil }, func(err error) error { return err }, nil
+	}
+	action, err := opts.existingArchive(ctx, srcRef, sourceCtx, retryOpts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if action == archiveSkip {
+		return nil, nil, nil, nil
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, nil, nil, i18n.Errorf("creating temporary file: %w", err)
+	}
+	tempPath := temp.Name()
+	removeTemp := func() bool {
+		if err := os.Remove(tempPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logrus.Warnf("Removing %s: %v", tempPath, err)
+			return false
+		}
+		return true
+	}
+	stopRemoving := atInterrupt(func() { removeTemp() })
+	err = temp.Chmod(0o644) // As docker-archive: creates archives
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	var tempRef types.ImageReference
+	if err == nil {
+		tempRef, err = opts.newReference(tempPath)
+	}
+	if err != nil {
+		stopRemoving()
+		removeTemp()
+		return nil, nil, nil, i18n.Errorf("creating temporary file: %w", err)
+	}
+	restart := func() error {
+		// docker-archive: refuses to write to a non-empty file.
+		if err := os.Truncate(tempPath, 0); err != nil {
+			return i18n.Errorf("emptying temporary file: %w", err)
+		}
+		return nil
+	}
+	return tempRef, restart, func(copyErr error) error {
+		defer stopRemoving()
+		if copyErr == nil {
+			if err := os.Rename(tempPath, path); err != nil {
+				copyErr = i18n.Errorf("renaming %s to %s: %w", tempPath, path, err)
+			}
+		}
+		if copyErr != nil {
+			opts.removedTemp = removeTemp()
+		}
+		return copyErr
+	}, nil
+}
+
+// archiveAction is what writing an archive does with the file existing at its path.
+type archiveAction int
+
+const (
+	archiveCreate  archiveAction = iota // There is no file yet
+	archiveReplace                      // The file is replaced, with --force
+	archiveSkip                         // The file contains the image already, and is kept with --skip-existing
+)
+
+// existingArchive returns what writing the archive at opts.path would do with the file existing at that path,
+// or the error the copy fails with because of it.
+func (opts *outputFileOptions) existingArchive(ctx context.Context, srcRef types.ImageReference, sourceCtx *types.SystemContext,
+	retryOpts *retry.Options) (archiveAction, error) {
+	path := opts.path
+	if _, err := os.Lstat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return archiveCreate, nil
+		}
+		return 0, err
+	}
+	if opts.skipExisting {
+		same, err := opts.sameImage(ctx, srcRef, sourceCtx, retryOpts)
+		if err != nil {
+			return 0, err
+		}
+		if same {
+			return archiveSkip, nil
+		}
+		if !opts.force {
+			return 0, i18n.Errorf("%s already exists and contains a different image, use --force to replace it", path)
+		}
+	}
+	if !opts.force {
+		return 0, i18n.Errorf("%s already exists, use --force to replace it or --skip-existing to keep it", path)
+	}
+	return archiveReplace, nil
+}
+
+// sameImage returns true if the archive at opts.path contains the image srcRef refers to, i.e. an image with
+// the same config. An archive which can't be read in the format written is reported as not containing it.
+func (opts *outputFileOptions) sameImage(ctx context.Context, srcRef types.ImageReference, sourceCtx *types.SystemContext, retryOpts *retry.Options) (bool, error) {
+	archiveRef, err := opts.newReference(opts.path)
+	if err != nil {
+		return false, err
+	}
+	existing, err := configDigest(ctx, nil, archiveRef, &retry.Options{})
+	if err != nil {
+		logrus.Debugf("Not reusing %s: %v", opts.path, err)
+		return false, nil
+	}
+	wanted, err := configDigest(ctx, sourceCtx, srcRef, retryOpts)
+	if err != nil {
+		return false, err
+	}
+	logrus.Debugf("Image config of %s: %s, of %s: %s", opts.path, existing, transports.ImageName(srcRef), wanted)
+	return existing == wanted, nil
+}
+
+// configDigest returns the digest of the config of the image ref refers to, identifying the image
+// independently of its manifest format and layer compression.
+func configDigest(ctx context.Context, sys *types.SystemContext, ref types.ImageReference, retryOpts *retry.Options) (_ digest.Digest, retErr error) {
+	src, img, err := openImage(ctx, sys, ref, retryOpts)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing image", err)
+		}
+	}()
+	return img.ConfigInfo().Digest, nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/image/v5/docker/archive"
+	"github.com/containers/image/v5/types"
+
+	"gopull/pkgs/retry"
+)
+
+// TestOutputFileRestart checks that a copy attempt after a failed one can write the temporary archive again.
+func TestOutputFileRestart(t *testing.T) {
+	ctx := context.Background()
+	opts := &outputFileOptions{
+		path: filepath.Join(t.TempDir(), "redis.tar"),
+		newReference: func(path string) (types.ImageReference, error) {
+			return archive.NewReference(path, nil)
+		},
+	}
+	ref, restart, done, err := opts.begin(ctx, nil, nil, nil, &retry.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for attempt := range 2 {
+		if err := restart(); err != nil {
+			t.Fatal(err)
+		}
+		dest, err := ref.NewImageDestination(ctx, &types.SystemContext{})
+		if err != nil {
+			t.Fatalf("attempt %d: %v", attempt, err)
+		}
+		// A failed attempt leaves a partial archive behind.
+		if err := dest.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(ref.StringWithinTransport(), []byte("partial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := done(os.ErrClosed); err != os.ErrClosed {
+		t.Fatalf("unexpected result %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(opts.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("files left after a failed copy: %v", entries)
+	}
+}
+
+func TestOutputFileExistingArchive(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.tar")
+	// Not an archive, so never the same image, and the source is not read.
+	if err := os.WriteFile(existing, []byte("not an archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		path                string
+		force, skipExisting bool
+		expected            archiveAction
+		ok                  bool
+	}{
+		{filepath.Join(dir, "new.tar"), false, false, archiveCreate, true},
+		{filepath.Join(dir, "new.tar"), false, true, archiveCreate, true},
+		{existing, false, false, 0, false},
+		{existing, true, false, archiveReplace, true},
+		{existing, false, true, 0, false},
+		{existing, true, true, archiveReplace, true},
+	} {
+		opts := &outputFileOptions{
+			force:        c.force,
+			skipExisting: c.skipExisting,
+			path:         c.path,
+			newReference: func(path string) (types.ImageReference, error) {
+				return archive.NewReference(path, nil)
+			},
+		}
+		action, err := opts.existingArchive(ctx, nil, nil, &retry.Options{})
+		if ok := err == nil; ok != c.ok || (ok && action != c.expected) {
+			t.Errorf("%s, force %v, skip-existing %v: %v, %v, expected %v, success %v", filepath.Base(c.path), c.force, c.skipExisting,
+				action, err, c.expected, c.ok)
+		}
+	}
+}
@@ -0,0 +1,553 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
+	"github.com/containers/storage/pkg/homedir"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
+)
+
+const (
+	// systemPolicyPath is the system-wide trust policy, used if there is no user policy.
+	systemPolicyPath = "/etc/containers/policy.json"
+	// builtinPolicySource is reported instead of a path when the built-in policy is used.
+	builtinPolicySource = "(built-in default)"
+)
+
+// userPolicyPath returns the path of the per-user trust policy, which takes precedence over systemPolicyPath.
+func userPolicyPath() string {
+	return filepath.Join(homedir.Get(), ".config/containers/policy.json")
+}
+
+// builtinPolicy returns the policy used when no policy file exists.
+// Like the policy.json shipped by distributions, it accepts any image; signatures can be required
+// using (gopull policy) or --verify-key/--verify-gpg.
+func builtinPolicy() *signature.Policy {
+	return &signature.Policy{
+		Default: signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()},
+		Transports: map[string]signature.PolicyTransportScopes{
+			"docker-daemon": {"": signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()}},
+		},
+	}
+}
+
+// loadPolicy returns the trust policy selected by opts, and the path it was read from (or builtinPolicySource).
+// Without --policy, the user policy, the system policy, and the built-in default are tried in that order.
+func (opts *globalOptions) loadPolicy() (*signature.Policy, string, error) {
+	paths := []string{opts.policyPath}
+	if opts.policyPath == "" {
+		paths = []string{userPolicyPath(), systemPolicyPath}
+	}
+	for _, path := range paths {
+		policy, err := signature.NewPolicyFromFile(path)
+		if err == nil {
+			return policy, path, nil
+		}
+		if opts.policyPath != "" || !errors.Is(err, os.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+	logrus.Debugf("No trust policy found, using the built-in default policy")
+	return builtinPolicy(), i18n.T(builtinPolicySource), nil
+}
+
+// editablePolicyPath returns the policy file (gopull policy) commands modify: --policy if set, otherwise the user policy.
+func (opts *globalOptions) editablePolicyPath() string {
+	if opts.policyPath != "" {
+		return opts.policyPath
+	}
+	return userPolicyPath()
+}
+
+// writePolicy writes policy to path, creating parent directories as needed.
+// The file is replaced atomically, so that a concurrent reader never sees a partial policy.
+func writePolicy(path string, policy *signature.Policy) (retErr error) {
+	data, err := json.MarshalIndent(policy, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".policy.json-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// requirementOptions collects the CLI flags describing a single policy requirement.
+type requirementOptions struct {
+	requirementType string // "accept", "reject", "signedBy" or "sigstoreSigned"
+	keyPath         string // Public key for signedBy (a GPG keyring) and sigstoreSigned
+}
+
+// requirementFlags prepares a collection of CLI flags writing into requirementOptions, and the managed requirementOptions structure.
+func requirementFlags() (pflag.FlagSet, *requirementOptions) {
+	opts := requirementOptions{}
+	fs := pflag.FlagSet{}
+	fs.StringVar(&opts.requirementType, "type", "", i18n.T("requirement `TYPE`: accept, reject, signedBy (GPG) or sigstoreSigned"))
+	fs.StringVar(&opts.keyPath, "key", "", i18n.T("public key at `PATH` for signedBy (a GPG keyring) and sigstoreSigned"))
+	return fs, &opts
+}
+
+// requirements returns the policy requirements described by opts.
+func (opts *requirementOptions) requirements() (signature.PolicyRequirements, error) {
+	needsKey := opts.requirementType == "signedBy" || opts.requirementType == "sigstoreSigned"
+	if needsKey != (opts.keyPath != "") {
+		if needsKey {
+			return nil, i18n.Errorf("--key is required for --type %s", opts.requirementType)
+		}
+		return nil, i18n.Errorf("--key can not be used with --type %s", opts.requirementType)
+	}
+	var keyPath string
+	if needsKey {
+		// The policy may be used from other working directories.
+		abs, err := filepath.Abs(opts.keyPath)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return nil, err
+		}
+		keyPath = abs
+	}
+
+	var req signature.PolicyRequirement
+	var err error
+	switch opts.requirementType {
+	case "accept", "insecureAcceptAnything":
+		req = signature.NewPRInsecureAcceptAnything()
+	case "reject":
+		req = signature.NewPRReject()
+	case "signedBy":
+		req, err = signature.NewPRSignedByKeyPath(signature.SBKeyTypeGPGKeys, keyPath, signature.NewPRMMatchRepoDigestOrExact())
+	case "sigstoreSigned":
+		req, err = signature.NewPRSigstoreSignedKeyPath(keyPath, signature.NewPRMMatchRepoDigestOrExact())
+	case "":
+		return nil, i18n.New("--type is required")
+	default:
+		return nil, i18n.Errorf("unknown requirement type %q, expected accept, reject, signedBy or sigstoreSigned", opts.requirementType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return signature.PolicyRequirements{req}, nil
+}
+
+// describeRequirement returns a one-line, human-readable description of req.
+func describeRequirement(req signature.PolicyRequirement) string {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Sprintf("%#v", req)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return string(data)
+	}
+	typ, _ := fields["type"].(string)
+	delete(fields, "type")
+	if len(fields) == 0 {
+		return typ
+	}
+	details, err := json.Marshal(fields)
+	if err != nil {
+		return typ
+	}
+	return typ + " " + string(details)
+}
+
+func policyCmd(global *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: i18n.T("Create and manage trust policies"),
+		Long: fmt.Sprintf(i18n.T(`Create and manage the trust policy deciding which images may be pulled.
+
+The policy is read from --policy, or else from %s, or else from
+%s. If none exists, a built-in default accepting any image is used.
+
+The init, add-registry and set-default commands modify the --policy file, or else the
+per-user policy file.`), userPolicyPath(), systemPolicyPath),
+	}
+	cmd.AddCommand(
+		policyInitCmd(global),
+		policyShowCmd(global),
+		policyAddRegistryCmd(global),
+		policySetDefaultCmd(global),
+		policyTestCmd(global),
+	)
+	return cmd
+}
+
+type policyInitOptions struct {
+	global *globalOptions
+	force  bool
+}
+
+func policyInitCmd(global *globalOptions) *cobra.Command {
+	opts := policyInitOptions{global: global}
+	cmd := &cobra.Command{
+		Use:   "init [command options]",
+		Short: i18n.T("Create a policy file with the built-in default policy"),
+		RunE:  commandAction(opts.run),
+		Example: `gopull policy init
+gopull --policy ./policy.json policy init --force`,
+	}
+	adjustUsage(cmd)
+	cmd.Flags().BoolVar(&opts.force, "force", false, i18n.T("Overwrite an existing policy file"))
+	return cmd
+}
+
+func (opts *policyInitOptions) run(args []string, stdout io.Writer) error {
+	if len(args) != 0 {
+		return errorShouldDisplayUsage{i18n.New("No arguments expected")}
+	}
+	path := opts.global.editablePolicyPath()
+	if _, err := os.Stat(path); err == nil && !opts.force {
+		return i18n.Errorf("policy file %s already exists, use --force to overwrite it", path)
+	}
+	if err := writePolicy(path, builtinPolicy()); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, i18n.T("Wrote %s\n"), path)
+	return nil
+}
+
+type policyShowOptions struct {
+	global *globalOptions
+}
+
+func policyShowCmd(global *globalOptions) *cobra.Command {
+	opts := policyShowOptions{global: global}
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: i18n.T("Show the effective policy, and the file it is read from"),
+		RunE:  commandAction(opts.run),
+	}
+	adjustUsage(cmd)
+	return cmd
+}
+
+func (opts *policyShowOptions) run(args []string, stdout io.Writer) error {
+	if len(args) != 0 {
+		return errorShouldDisplayUsage{i18n.New("No arguments expected")}
+	}
+	policy, source, err := opts.global.loadPolicy()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(policy, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "# %s\n%s\n", source, data)
+	return nil
+}
+
+type policyAddRegistryOptions struct {
+	global      *globalOptions
+	requirement *requirementOptions
+	transport   string
+}
+
+func policyAddRegistryCmd(global *globalOptions) *cobra.Command {
+	requirementFlags, requirementOpts := requirementFlags()
+	opts := policyAddRegistryOptions{global: global, requirement: requirementOpts}
+	cmd := &cobra.Command{
+		Use:   "add-registry [command options] SCOPE",
+		Short: i18n.T("Set the requirement for images in SCOPE"),
+		Long: i18n.T(`Set the requirement for images in SCOPE, replacing any existing requirement for SCOPE.
+
+For the docker transport, SCOPE is a registry ("registry.example.com"), a namespace
+or repository ("registry.example.com/team/app"), a single image ("...app:1.0"), or a
+wildcard of registries ("*.example.com"). The most specific scope matching an image applies.`),
+		RunE: commandAction(opts.run),
+		Example: `gopull policy add-registry --type sigstoreSigned --key cosign.pub registry.example.com/team
+gopull policy add-registry --type signedBy --key pubring.gpg registry.access.redhat.com
+gopull policy add-registry --type reject docker.io`,
+	}
+	adjustUsage(cmd)
+	flags := cmd.Flags()
+	flags.AddFlagSet(&requirementFlags)
+	flags.StringVar(&opts.transport, "transport", "docker", i18n.T("`TRANSPORT` the scope applies to"))
+	return cmd
+}
+
+func (opts *policyAddRegistryOptions) run(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errorShouldDisplayUsage{i18n.New("Exactly one argument expected")}
+	}
+	scope := args[0]
+	transport := transports.Get(opts.transport)
+	if transport == nil {
+		return i18n.Errorf("unknown transport %q", opts.transport)
+	}
+	if err := transport.ValidatePolicyConfigurationScope(scope); err != nil {
+		return i18n.Errorf("invalid scope %q: %w", scope, err)
+	}
+	requirements, err := opts.requirement.requirements()
+	if err != nil {
+		return err
+	}
+
+	path := opts.global.editablePolicyPath()
+	policy, err := loadEditablePolicy(path)
+	if err != nil {
+		return err
+	}
+	if policy.Transports == nil {
+		policy.Transports = map[string]signature.PolicyTransportScopes{}
+	}
+	if policy.Transports[opts.transport] == nil {
+		policy.Transports[opts.transport] = signature.PolicyTransportScopes{}
+	}
+	policy.Transports[opts.transport][scope] = requirements
+	if err := writePolicy(path, policy); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, i18n.T("Set %s scope %q to %s in %s\n"), opts.transport, scope, describeRequirement(requirements[0]), path)
+	return nil
+}
+
+type policySetDefaultOptions struct {
+	global      *globalOptions
+	requirement *requirementOptions
+}
+
+func policySetDefaultCmd(global *globalOptions) *cobra.Command {
+	requirementFlags, requirementOpts := requirementFlags()
+	opts := policySetDefaultOptions{global: global, requirement: requirementOpts}
+	cmd := &cobra.Command{
+		Use:   "set-default [command options]",
+		Short: i18n.T("Set the requirement for images not matching any scope"),
+		RunE:  commandAction(opts.run),
+		Example: `gopull policy set-default --type reject
+gopull policy set-default --type sigstoreSigned --key cosign.pub`,
+	}
+	adjustUsage(cmd)
+	cmd.Flags().AddFlagSet(&requirementFlags)
+	return cmd
+}
+
+func (opts *policySetDefaultOptions) run(args []string, stdout io.Writer) error {
+	if len(args) != 0 {
+		return errorShouldDisplayUsage{i18n.New("No arguments expected")}
+	}
+	requirements, err := opts.requirement.requirements()
+	if err != nil {
+		return err
+	}
+	path := opts.global.editablePolicyPath()
+	policy, err := loadEditablePolicy(path)
+	if err != nil {
+		return err
+	}
+	policy.Default = requirements
+	if err := writePolicy(path, policy); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, i18n.T("Set the default requirement to %s in %s\n"), describeRequirement(requirements[0]), path)
+	return nil
+}
+
+// loadEditablePolicy reads the policy at path, or returns the built-in default policy if it does not exist yet.
+func loadEditablePolicy(path string) (*signature.Policy, error) {
+	policy, err := signature.NewPolicyFromFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return builtinPolicy(), nil
+	}
+	return policy, err
+}
+
+type policyTestOptions struct {
+	global    *globalOptions
+	image     *imageOptions
+	retryOpts *retry.Options
+}
+
+func policyTestCmd(global *globalOptions) *cobra.Command {
+	sharedFlags, sharedOpts := sharedImageFlags()
+	imageFlags, imageOpts := imageFlags(global, sharedOpts, nil, "", "")
+	retryFlags, retryOpts := retryFlags()
+	opts := policyTestOptions{
+		global:    global,
+		image:     imageOpts,
+		retryOpts: retryOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "test [command options] IMAGE-NAME",
+		Short: i18n.T("Explain whether the policy accepts image IMAGE-NAME"),
+		Long: i18n.T(`Show which scope of the policy applies to "IMAGE-NAME" and its requirements, and whether
+the image would be accepted. Signatures are only read if a requirement needs them.`),
+		RunE: commandAction(opts.run),
+		Example: `gopull policy test redis
+gopull policy test registry.example.com/team/app:1.0`,
+		ValidArgsFunction: autocompleteSupportedTransports,
+	}
+	adjustUsage(cmd)
+	flags := cmd.Flags()
+	flags.AddFlagSet(&sharedFlags)
+	flags.AddFlagSet(&imageFlags)
+	flags.AddFlagSet(&retryFlags)
+	return cmd
+}
+
+// policyScopeFor returns the requirements the policy applies to ref, and a description of the section they come from.
+// This follows the lookup order of signature.PolicyContext.
+func policyScopeFor(policy *signature.Policy, ref types.ImageReference) (signature.PolicyRequirements, string) {
+	transportName := ref.Transport().Name()
+	if scopes, ok := policy.Transports[transportName]; ok {
+		if req, ok := scopes[ref.PolicyConfigurationIdentity()]; ok {
+			return req, fmt.Sprintf(i18n.T("transport %q, scope %q (exact match)"), transportName, ref.PolicyConfigurationIdentity())
+		}
+		for _, name := range ref.PolicyConfigurationNamespaces() {
+			if req, ok := scopes[name]; ok {
+				return req, fmt.Sprintf(i18n.T("transport %q, scope %q"), transportName, name)
+			}
+		}
+		if req, ok := scopes[""]; ok {
+			return req, fmt.Sprintf(i18n.T("transport %q, default scope"), transportName)
+		}
+	}
+	return policy.Default, i18n.T("default")
+}
+
+func (opts *policyTestOptions) run(args []string, stdout io.Writer) (retErr error) {
+	if len(args) != 1 {
+		return errorShouldDisplayUsage{i18n.New("Exactly one argument expected")}
+	}
+	arg, err := opts.global.expandAlias(args[0])
+	if err != nil {
+		return err
+	}
+	imageName, err := sourceImageName(arg)
+	if err != nil {
+		return err
+	}
+	// A short name is explained for the first registry it would be pulled from.
+	imageNames, err := opts.global.resolveSourceImageName(imageName)
+	if err != nil {
+		return err
+	}
+	imageName = imageNames.candidates[0]
+	ref, err := alltransports.ParseImageName(imageName)
+	if err != nil {
+		return invalidReferenceError{i18n.Errorf("Error parsing image name %q: %w", imageName, err)}
+	}
+	policy, source, err := opts.global.loadPolicy()
+	if err != nil {
+		return err
+	}
+
+	requirements, section := policyScopeFor(policy, ref)
+	fmt.Fprintf(stdout, i18n.T("Image: %s\n"), transports.ImageName(ref))
+	fmt.Fprintf(stdout, i18n.T("Policy: %s\n"), source)
+	fmt.Fprintf(stdout, i18n.T("Section: %s\n"), section)
+	fmt.Fprint(stdout, i18n.T("Requirements:\n"))
+	needsSignatures := false
+	for _, req := range requirements {
+		description := describeRequirement(req)
+		fmt.Fprintf(stdout, "  - %s\n", description)
+		typ, _, _ := strings.Cut(description, " ")
+		if typ != "insecureAcceptAnything" && typ != "reject" {
+			needsSignatures = true
+		}
+	}
+
+	policyContext, err := signature.NewPolicyContext(policy)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := policyContext.Destroy(); err != nil {
+			retErr = noteCloseFailure(retErr, "tearing down policy context", err)
+		}
+	}()
+	ctx, cancel := opts.global.commandTimeoutContext()
+	defer cancel()
+	var unparsed types.UnparsedImage = referenceOnlyImage{ref: ref}
+	if needsSignatures {
+		sys, err := opts.image.newSystemContextFor(ref)
+		if err != nil {
+			return err
+		}
+		cleanup, err := enableSigstoreAttachments(sys, ref)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		src, _, err := openImage(ctx, sys, ref, opts.retryOpts)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := src.Close(); err != nil {
+				retErr = noteCloseFailure(retErr, "closing image", err)
+			}
+		}()
+		unparsed = image.UnparsedInstance(src, nil)
+	}
+	if allowed, err := policyContext.IsRunningImageAllowed(ctx, unparsed); !allowed {
+		fmt.Fprintf(stdout, i18n.T("Result: rejected: %v\n"), err)
+		return policyRejectedError{err}
+	}
+	fmt.Fprint(stdout, i18n.T("Result: accepted\n"))
+	return nil
+}
+
+// policyRejectedError is returned by (policy test) for a rejected image. The reason is already written to stdout,
+// so it is not repeated in the message; it is wrapped for the exit code of a rejection.
+type policyRejectedError struct {
+	err error
+}
+
+func (e policyRejectedError) Error() string {
+	return i18n.T("image rejected by the policy")
+}
+
+func (e policyRejectedError) Unwrap() error {
+	return e.err
+}
+
+// referenceOnlyImage is a types.UnparsedImage for evaluating requirements which only look at the image reference,
+// so that (policy test) does not need to access the image for them.
+type referenceOnlyImage struct {
+	types.UnparsedImage // nil, calling any other method fails
+	ref                 types.ImageReference
+}
+
+func (i referenceOnlyImage) Reference() types.ImageReference {
+	return i.ref
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/types"
+	"github.com/sirupsen/logrus"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/progress"
+	"gopull/pkgs/retry"
+)
+
+// Values of --progress.
+const (
+	progressText = "text" // Human-readable lines and totals on stderr
+	progressJSON = "json" // progress.Event values, one JSON object per line
+)
+
+// progressInterval is how often copy.Image reports the bytes transferred for each blob.
+const progressInterval = 500 * time.Millisecond
+
+// checkProgressFormat returns an error if format is not a supported --progress value.
+func checkProgressFormat(format string) error {
+	switch format {
+	case progressText, progressJSON:
+		return nil
+	}
+	return i18n.Errorf("unknown progress format %q, expected %s or %s", format, progressText, progressJSON)
+}
+
+// progressEmitter reports the progress of copies as progress.Event values, handled by a separate goroutine.
+type progressEmitter struct {
+	events chan progress.Event
+	done   chan error // Receives the result of the handler once events is closed
+
+	blobs  []progress.Blob // The blobs of the source image, listed by the first attempt of copyImage
+	listed bool            // The blobs were listed, even if they could not be read
+	failed error           // The failure of the last attempt of copyImage, already reported; nil if it succeeded
+}
+
+// newProgressEmitter returns a progressEmitter passing events to handler, e.g. progress.WriteJSON.
+// handler must consume events until the channel is closed.
+// The caller must call close when done.
+func newProgressEmitter(handler func(events <-chan progress.Event) error) *progressEmitter {
+	e := &progressEmitter{
+		events: make(chan progress.Event, 64),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		e.done <- handler(e.events)
+	}()
+	return e
+}
+
+// emit reports ev, setting its time.
+func (e *progressEmitter) emit(ev progress.Event) {
+	ev.Time = time.Now()
+	e.events <- ev
+}
+
+// close waits for all events to be handled, and returns the error of the handler, if any.
+func (e *progressEmitter) close() error {
+	close(e.events)
+	return <-e.done
+}
+
+// copyImage is copy.Image, reporting the start and the outcome of the copy, and the progress of each blob, to e.
+// rateLimit is the bandwidth limit of the copy in bytes per second, or 0; it is only reported.
+// options.Progress and options.ProgressInterval are overwritten.
+// An emitter reports a single copy, which may be attempted several times; the source blobs are only listed once.
+func (e *progressEmitter) copyImage(ctx context.Context, policyContext *signature.PolicyContext, destRef, srcRef types.ImageReference, options *copy.Options, rateLimit int64) ([]byte, error) {
+	image := progress.Event{
+		Source:      transports.ImageName(srcRef),
+		Destination: transports.ImageName(destRef),
+	}
+	started := image
+	started.Type = progress.ImageStarted
+	started.RateLimit = rateLimit
+	if !e.listed {
+		e.blobs = sourceBlobs(ctx, options.SourceCtx, srcRef)
+		e.listed = true
+	}
+	started.Blobs = e.blobs
+	e.emit(started)
+
+	ch := make(chan types.ProgressProperties)
+	forwarded := make(chan struct{})
+	go func() {
+		progress.Forward(ch, e.events)
+		close(forwarded)
+	}()
+	options.Progress = ch
+	options.ProgressInterval = progressInterval
+	copiedManifest, err := copy.Image(ctx, policyContext, destRef, srcRef, options)
+	// copy.Image does not send anything after returning.
+	close(ch)
+	<-forwarded
+
+	if err != nil {
+		failed := image
+		failed.Type = progress.Error
+		failed.Error = err.Error()
+		e.emit(failed)
+		e.failed = err
+		return nil, err
+	}
+	e.failed = nil
+	done := image
+	if manifestDigest, err := manifest.Digest(copiedManifest); err == nil {
+		done.Digest = manifestDigest.String()
+	}
+	done.MediaType = manifest.GuessMIMEType(copiedManifest)
+	written := done
+	written.Type = progress.ManifestWritten
+	e.emit(written)
+	done.Type = progress.ImageDone
+	e.emit(done)
+	return copiedManifest, nil
+}
+
+// fail reports that the copy of source to destination failed with err, unless the copy itself already did,
+// e.g. when the destination can't be written to or options are invalid.
+func (e *progressEmitter) fail(source, destination string, err error) {
+	if e.failed != nil && errors.Is(err, e.failed) {
+		return
+	}
+	e.emit(progress.Event{Type: progress.Error, Source: source, Destination: destination, Error: err.Error()})
+	e.failed = err
+}
+
+// sourceBlobs returns the config and layers of the image srcRef refers to, so that the totals of the progress
+// include them before they are copied; or nil if they can't be read, which the copy then reports.
+func sourceBlobs(ctx context.Context, sys *types.SystemContext, srcRef types.ImageReference) (res []progress.Blob) {
+	src, img, err := openImage(ctx, sys, srcRef, &retry.Options{})
+	if err != nil {
+		logrus.Debugf("Not listing the blobs of %s for the progress: %v", transports.ImageName(srcRef), err)
+		return nil
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			logrus.Debugf("Closing image: %v", err)
+		}
+	}()
+	if config := img.ConfigInfo(); config.Digest != "" {
+		res = append(res, progress.Blob{Digest: config.Digest.String(), MediaType: config.MediaType, Size: config.Size})
+	}
+	for _, layer := range img.LayerInfos() {
+		res = append(res, progress.Blob{Digest: layer.Digest.String(), MediaType: layer.MediaType, Size: layer.Size})
+	}
+	return res
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"gopull/pkgs/progress"
+)
+
+func TestProgressEmitterFail(t *testing.T) {
+	var events []progress.Event
+	e := newProgressEmitter(func(ch <-chan progress.Event) error {
+		for ev := range ch {
+			events = append(events, ev)
+		}
+		return nil
+	})
+	copyErr := errors.New("writing blob: connection reset")
+	e.failed = copyErr // As copyImage records it
+	e.fail("docker://alpine", "oci-archive:a.tar", fmt.Errorf("renaming: %w", copyErr))
+	e.fail("docker://alpine", "oci-archive:a.tar", errors.New("a.tar already exists"))
+	if err := e.close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d events, expected 1: %#v", len(events), events)
+	}
+	if ev := events[0]; ev.Type != progress.Error || ev.Error != "a.tar already exists" || ev.Source != "docker://alpine" {
+		t.Errorf("%#v", ev)
+	}
+}
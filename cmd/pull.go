@@ -11,6 +11,8 @@ import (
 	"github.com/containers/image/v5/transports/alltransports"
 	"github.com/containers/image/v5/types"
 	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
 )
 
 type pullOptions struct {
@@ -25,6 +27,9 @@ func pull(global *globalOptions) *cobra.Command {
 	srcFlags, srcOpts := imageFlags(global, sharedOpts, deprecatedTLSVerifyOpt, "src-", "screds")
 	destFlags, destOpts := imageDestFlags(global, sharedOpts, deprecatedTLSVerifyOpt, "dest-", "dcreds")
 	retryFlags, retryOpts := retryFlags()
+	verifyFlags, verifyOpts := verifyFlags()
+	rateLimitFlags, rateLimitOpts := rateLimitFlags(global)
+	decryptFlags, decryptOpts := decryptionFlags()
 	opts := pullOptions{
 		copyOptions: &copyOptions{
 			global:              global,
@@ -32,20 +37,27 @@ func pull(global *globalOptions) *cobra.Command {
 			srcImage:            srcOpts,
 			destImage:           destOpts,
 			retryOpts:           retryOpts,
+			verify:              verifyOpts,
+			rateLimit:           rateLimitOpts,
+			decrypt:             decryptOpts,
+			resolveShortNames:   true,
 		},
 	}
 	cmd := &cobra.Command{
 		Use:   "pull [command options] IMAGE ",
-		Short: "pull an image to docker",
-		Long: fmt.Sprintf(`Container "IMAGE-NAME" uses a "transport":"details" format.
+		Short: i18n.T("pull an image to docker"),
+		Long: fmt.Sprintf(i18n.T(`Container "IMAGE-NAME" uses a "transport":"details" format.
 
 Supported transports:
 %s
 
 See skopeo(1) section "IMAGE NAMES" for the expected format
-`, strings.Join(transports.ListNames(), ", ")),
-		RunE:              commandAction(opts.run),
-		Example:           `gopull pull redis`,
+`), strings.Join(transports.ListNames(), ", ")),
+		RunE: commandAction(opts.run),
+		Example: `gopull pull redis
+gopull pull --verify-key cosign.pub registry.example.com/app:1.0
+gopull pull --verify-gpg pubring.gpg registry.example.com/app:1.0
+gopull pull --decryption-key priv.pem registry.example.com/app:1.0`,
 		ValidArgsFunction: autocompleteSupportedTransports,
 	}
 	adjustUsage(cmd)
@@ -55,9 +67,14 @@ See skopeo(1) section "IMAGE NAMES" for the expected format
 	flags.AddFlagSet(&srcFlags)
 	flags.AddFlagSet(&destFlags)
 	flags.AddFlagSet(&retryFlags)
-	flags.BoolVarP(&opts.quiet, "quiet", "q", false, "Suppress output information when copying images")
-	flags.VarP(commonFlag.NewOptionalStringValue(&opts.format), "format", "f", `MANIFEST TYPE (oci, v2s1, or v2s2) to use in the destination (default is manifest type of source, with fallbacks)`)
-	flags.StringVarP(&opts.addTag, "tag", "t", "", "set dest tag")
+	flags.AddFlagSet(&verifyFlags)
+	flags.AddFlagSet(&rateLimitFlags)
+	flags.AddFlagSet(&decryptFlags)
+	flags.BoolVarP(&opts.quiet, "quiet", "q", false, i18n.T("Suppress output information when copying images"))
+	flags.BoolVar(&opts.dryRun, "dry-run", false, i18n.T("Report the blobs and tags the pull would transfer and write, without writing anything"))
+	flags.StringVar(&opts.progress, "progress", progressText, i18n.T("Progress output `FORMAT`, text or json (newline-delimited events on standard output)"))
+	flags.VarP(commonFlag.NewOptionalStringValue(&opts.format), "format", "f", i18n.T(`MANIFEST TYPE (oci, v2s1, or v2s2) to use in the destination (default is manifest type of source, with fallbacks)`))
+	flags.StringVarP(&opts.addTag, "tag", "t", "", i18n.T("set dest tag"))
 	return cmd
 }
 
@@ -69,9 +86,13 @@ func (opts *pullOptions) buildSrcRef(imageName string) (types.ImageReference, *t
 
 	srcRef, err := alltransports.ParseImageName("docker://" + imageName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("invalid source name %s: %v", imageName, err)
+		return nil, nil, invalidReferenceError{i18n.Errorf("invalid source name %s: %v", imageName, err)}
+	}
+	srcRef, err = opts.global.mirrorReference(srcRef)
+	if err != nil {
+		return nil, nil, err
 	}
-	sourceCtx, err := opts.srcImage.newSystemContext()
+	sourceCtx, err := opts.srcImage.newSystemContextFor(srcRef)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -82,11 +103,15 @@ func (opts *pullOptions) buildDestRef(imageName string) (types.ImageReference, *
 
 	parsedImage, err := image.ParseImageStr(imageName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parse image faild name %s: %v", imageName, err)
+		return nil, nil, invalidReferenceError{i18n.Errorf("failed to parse image name %s: %v", imageName, err)}
 	}
 	dest := "docker-daemon:"
 
-	destTag, err := buildDestTag(parsedImage, opts.addTag)
+	tag, err := opts.global.expandAlias(opts.addTag)
+	if err != nil {
+		return nil, nil, err
+	}
+	destTag, err := buildDestTag(parsedImage, tag)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -94,7 +119,7 @@ func (opts *pullOptions) buildDestRef(imageName string) (types.ImageReference, *
 
 	destRef, err := alltransports.ParseImageName(dest)
 	if err != nil {
-		return nil, nil, fmt.Errorf("invalid destination name %s: %v", dest, err)
+		return nil, nil, invalidReferenceError{i18n.Errorf("invalid destination name %s: %v", dest, err)}
 	}
 
 	destCtx, err := opts.destImage.newSystemContext()
@@ -113,6 +138,5 @@ func buildDestTag(parsedImage image.ImageStruct, addTag string) (string, error)
 		return getDestTagFormImageStruct(parsedImage), nil
 	}
 
-	return "", fmt.Errorf(`源镜像名称(redis@%s...) 包含 digest, 
-	需要显示的设置目标tag, 请使用 -t 或者 --tag 参数来设置`, parsedImage.Digest[:19])
+	return "", i18n.Errorf("the source image name (redis@%s...) contains a digest, the destination tag must be set explicitly with -t or --tag", parsedImage.Digest[:19])
 }
@@ -10,6 +10,8 @@ import (
 	"github.com/containers/image/v5/transports/alltransports"
 	"github.com/containers/image/v5/types"
 	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
 )
 
 type pushOptions struct {
@@ -23,6 +25,9 @@ func push(global *globalOptions) *cobra.Command {
 	srcFlags, srcOpts := imageFlags(global, sharedOpts, deprecatedTLSVerifyOpt, "src-", "screds")
 	destFlags, destOpts := imageDestFlags(global, sharedOpts, deprecatedTLSVerifyOpt, "dest-", "dcreds")
 	retryFlags, retryOpts := retryFlags()
+	signFlags, signOpts := signFlags()
+	rateLimitFlags, rateLimitOpts := rateLimitFlags(global)
+	encryptFlags, encryptOpts := encryptionFlags()
 	opts := pushOptions{
 		copyOptions: &copyOptions{
 			global:              global,
@@ -30,21 +35,32 @@ func push(global *globalOptions) *cobra.Command {
 			srcImage:            srcOpts,
 			destImage:           destOpts,
 			retryOpts:           retryOpts,
+			sign:                signOpts,
+			rateLimit:           rateLimitOpts,
+			encrypt:             encryptOpts,
 		},
 	}
 	cmd := &cobra.Command{
 		Use:   "push [command options] IMAGE ",
-		Short: "push an image",
-		Long: fmt.Sprintf(`Container "IMAGE-NAME" uses a "transport":"details" format.
+		Short: i18n.T("push an image"),
+		Long: fmt.Sprintf(i18n.T(`Container "IMAGE-NAME" uses a "transport":"details" format.
+
+With --sign-by or --sign-by-sigstore-private-key the pushed image is signed. sigstore
+signatures are stored in the registry as attachments, GPG signatures in the lookaside
+storage configured in registries.d (see --registries.d).
 
 Supported transports:
 %s
 
 See skopeo(1) section "IMAGE NAMES" for the expected format
-`, strings.Join(transports.ListNames(), ", ")),
+`), strings.Join(transports.ListNames(), ", ")),
 		RunE: commandAction(opts.run),
 		Example: `gopull push redis
 gopull push redis -t example.harbor.org/redis:v1
+gopull push --sign-by-sigstore-private-key cosign.key --sign-passphrase-file passphrase.txt redis -t example.harbor.org/redis:v1
+gopull push --sign-by 0123456789ABCDEF0123456789ABCDEF01234567 redis -t example.harbor.org/redis:v1
+gopull push --limit-rate 2M redis -t example.harbor.org/redis:v1
+gopull push --encryption-key jwe:pub.pem redis -t example.harbor.org/redis:v1
 `,
 		ValidArgsFunction: autocompleteSupportedTransports,
 	}
@@ -55,9 +71,14 @@ gopull push redis -t example.harbor.org/redis:v1
 	flags.AddFlagSet(&srcFlags)
 	flags.AddFlagSet(&destFlags)
 	flags.AddFlagSet(&retryFlags)
-	flags.BoolVarP(&opts.quiet, "quiet", "q", false, "Suppress output information when copying images")
-	flags.VarP(commonFlag.NewOptionalStringValue(&opts.format), "format", "f", `MANIFEST TYPE (oci, v2s1, or v2s2) to use in the destination (default is manifest type of source, with fallbacks)`)
-	flags.StringVarP(&opts.destTag, "--tag", "t", "", "Push destination")
+	flags.AddFlagSet(&signFlags)
+	flags.AddFlagSet(&rateLimitFlags)
+	flags.AddFlagSet(&encryptFlags)
+	flags.BoolVarP(&opts.quiet, "quiet", "q", false, i18n.T("Suppress output information when copying images"))
+	flags.BoolVar(&opts.dryRun, "dry-run", false, i18n.T("Report the blobs and tags the push would transfer and write, without writing anything"))
+	flags.StringVar(&opts.progress, "progress", progressText, i18n.T("Progress output `FORMAT`, text or json (newline-delimited events on standard output)"))
+	flags.VarP(commonFlag.NewOptionalStringValue(&opts.format), "format", "f", i18n.T(`MANIFEST TYPE (oci, v2s1, or v2s2) to use in the destination (default is manifest type of source, with fallbacks)`))
+	flags.StringVarP(&opts.destTag, "--tag", "t", "", i18n.T("Push destination"))
 	return cmd
 }
 
@@ -69,7 +90,7 @@ func (opts *pushOptions) buildSrcRef(imageName string) (types.ImageReference, *t
 
 	srcRef, err := alltransports.ParseImageName("docker-daemon:" + imageName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("invalid source name %s: %v", imageName, err)
+		return nil, nil, invalidReferenceError{i18n.Errorf("invalid source name %s: %v", imageName, err)}
 	}
 	sourceCtx, err := opts.srcImage.newSystemContext()
 	if err != nil {
@@ -82,15 +103,19 @@ func (opts *pushOptions) buildDestRef(imageName string) (types.ImageReference, *
 
 	dest := "docker://" + imageName
 	if opts.destTag != "" {
-		dest = "docker://" + opts.destTag
+		destTag, err := opts.global.expandAlias(opts.destTag)
+		if err != nil {
+			return nil, nil, err
+		}
+		dest = "docker://" + destTag
 	}
 
 	destRef, err := alltransports.ParseImageName(dest)
 	if err != nil {
-		return nil, nil, fmt.Errorf("invalid destination name %s: %v", dest, err)
+		return nil, nil, invalidReferenceError{i18n.Errorf("invalid destination name %s: %v", dest, err)}
 	}
 
-	destCtx, err := opts.destImage.newSystemContext()
+	destCtx, err := opts.destImage.newSystemContextFor(destRef)
 	if err != nil {
 		return nil, nil, err
 	}
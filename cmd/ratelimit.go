@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/types"
+	"github.com/distribution/reference"
+	"github.com/spf13/pflag"
+	"golang.org/x/time/rate"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/throttle"
+)
+
+// rateLimitOptions collects CLI flags limiting the bandwidth used to transfer blobs from and to registries.
+type rateLimitOptions struct {
+	limitRate      string   // Limit for all registries together, e.g. "5M"
+	registryLimits []string // REGISTRY=RATE limits for single registries
+
+	global     *globalOptions           // For limits of registries in the configuration file
+	total      *rate.Limiter            // Created on first use, shared by all transfers
+	registries map[string]*rate.Limiter // Created on first use, indexed by registry
+}
+
+// rateLimitFlags prepares a collection of CLI flags writing into rateLimitOptions, and the managed rateLimitOptions structure.
+func rateLimitFlags(global *globalOptions) (pflag.FlagSet, *rateLimitOptions) {
+	opts := rateLimitOptions{global: global}
+	fs := pflag.FlagSet{}
+	fs.StringVar(&opts.limitRate, "limit-rate", "", i18n.T("Limit the transfer of blobs from and to registries to `RATE` bytes per second in total, e.g. 500k or 5M"))
+	fs.StringArrayVar(&opts.registryLimits, "registry-limit-rate", nil, i18n.T("Limit the transfer of blobs from and to a single registry, as `REGISTRY=RATE` (can be repeated)"))
+	return fs, &opts
+}
+
+// limiters returns the limiters applying to blob transfers from or to ref.
+// Only registries are limited; local files and the docker daemon are not.
+func (opts *rateLimitOptions) limiters(ref types.ImageReference) ([]*rate.Limiter, error) {
+	if ref.Transport() != docker.Transport || ref.DockerReference() == nil {
+		return nil, nil
+	}
+	var res []*rate.Limiter
+	if opts.limitRate != "" {
+		if opts.total == nil {
+			limit, err := throttle.ParseRate(opts.limitRate)
+			if err != nil {
+				return nil, i18n.Errorf("--limit-rate: %w", err)
+			}
+			opts.total = throttle.NewLimiter(limit)
+		}
+		res = append(res, opts.total)
+	}
+	if opts.registries == nil {
+		opts.registries = map[string]*rate.Limiter{}
+		for _, value := range opts.registryLimits {
+			registry, limitRate, ok := strings.Cut(value, "=")
+			if !ok || registry == "" {
+				return nil, i18n.Errorf("--registry-limit-rate: %q is not in the REGISTRY=RATE format", value)
+			}
+			limit, err := throttle.ParseRate(limitRate)
+			if err != nil {
+				return nil, i18n.Errorf("--registry-limit-rate: %w", err)
+			}
+			opts.registries[registry] = throttle.NewLimiter(limit)
+		}
+	}
+	registry := reference.Domain(ref.DockerReference())
+	if _, ok := opts.registries[registry]; !ok {
+		// Limits on the command line replace those in the configuration file.
+		if r, ok := opts.global.registryConfig(ref); ok && r.LimitRate != "" {
+			limit, err := throttle.ParseRate(r.LimitRate)
+			if err != nil {
+				return nil, i18n.Errorf("limit-rate of registry %s in the configuration file: %w", registry, err)
+			}
+			opts.registries[registry] = throttle.NewLimiter(limit)
+		}
+	}
+	if l, ok := opts.registries[registry]; ok {
+		res = append(res, l)
+	}
+	return res, nil
+}
+
+// copyLimiters returns the limiters applying to the blobs copied from srcRef to destRef: those of the source
+// registry and of the destination registry, if any, each of them once.
+// The blobs of a copy between two registries go through both limits at the same time.
+func (opts *rateLimitOptions) copyLimiters(srcRef, destRef types.ImageReference) ([]*rate.Limiter, error) {
+	res, err := opts.limiters(srcRef)
+	if err != nil {
+		return nil, err
+	}
+	destLimiters, err := opts.limiters(destRef)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range destLimiters {
+		if !slices.Contains(res, l) {
+			res = append(res, l)
+		}
+	}
+	return res, nil
+}
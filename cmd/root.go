@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	commonFlag "github.com/containers/common/pkg/flag"
 	"github.com/containers/storage/pkg/reexec"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
 )
 
 // requireSubcommand returns an error if no sub command is provided
@@ -18,11 +21,11 @@ func requireSubcommand(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		suggestions := cmd.SuggestionsFor(args[0])
 		if len(suggestions) == 0 {
-			return fmt.Errorf("Unrecognized command `%[1]s %[2]s`\nTry '%[1]s --help' for more information", cmd.CommandPath(), args[0])
+			return i18n.Errorf("Unrecognized command `%[1]s %[2]s`\nTry '%[1]s --help' for more information", cmd.CommandPath(), args[0])
 		}
-		return fmt.Errorf("Unrecognized command `%[1]s %[2]s`\n\nDid you mean this?\n\t%[3]s\n\nTry '%[1]s --help' for more information", cmd.CommandPath(), args[0], strings.Join(suggestions, "\n\t"))
+		return i18n.Errorf("Unrecognized command `%[1]s %[2]s`\n\nDid you mean this?\n\t%[3]s\n\nTry '%[1]s --help' for more information", cmd.CommandPath(), args[0], strings.Join(suggestions, "\n\t"))
 	}
-	return fmt.Errorf("Missing command '%[1]s COMMAND'\nTry '%[1]s --help' for more information", cmd.CommandPath())
+	return i18n.Errorf("Missing command '%[1]s COMMAND'\nTry '%[1]s --help' for more information", cmd.CommandPath())
 }
 
 // createApp returns a cobra.Command, and the underlying globalOptions object, to be run or tested.
@@ -31,7 +34,7 @@ func createApp() (*cobra.Command, *globalOptions) {
 
 	rootCommand := &cobra.Command{
 		Use:               "gopull",
-		Long:              "Various operations with container images and container image registries",
+		Long:              i18n.T("Various operations with container images and container image registries"),
 		RunE:              requireSubcommand,
 		PersistentPreRunE: opts.before,
 		SilenceUsage:      true,
@@ -51,23 +54,37 @@ func createApp() (*cobra.Command, *globalOptions) {
 		rootCommand.Version = version
 	}
 	// Override default `--version` global flag to enable `-v` shorthand
-	rootCommand.Flags().BoolP("version", "v", false, "Version for Skopeo")
-	rootCommand.PersistentFlags().BoolVar(&opts.debug, "debug", false, "enable debug output")
-	rootCommand.PersistentFlags().StringVar(&opts.policyPath, "policy", "", "Path to a trust policy file")
-	rootCommand.PersistentFlags().BoolVar(&opts.insecurePolicy, "insecure-policy", false, "run the tool without any policy check")
-	rootCommand.PersistentFlags().StringVar(&opts.registriesDirPath, "registries.d", "", "use registry configuration files in `DIR` (e.g. for container signature storage)")
-	rootCommand.PersistentFlags().StringVar(&opts.overrideArch, "override-arch", "", "use `ARCH` instead of the architecture of the machine for choosing images")
-	rootCommand.PersistentFlags().StringVar(&opts.overrideOS, "override-os", "", "use `OS` instead of the running OS for choosing images")
-	rootCommand.PersistentFlags().StringVar(&opts.overrideVariant, "override-variant", "", "use `VARIANT` instead of the running architecture variant for choosing images")
-	rootCommand.PersistentFlags().DurationVar(&opts.commandTimeout, "command-timeout", 0, "timeout for the command execution")
-	rootCommand.PersistentFlags().StringVar(&opts.tmpDir, "tmpdir", "", "directory used to store temporary files")
-	flag := commonFlag.OptionalBoolFlag(rootCommand.Flags(), &opts.tlsVerify, "tls-verify", "Require HTTPS and verify certificates when accessing the registry")
+	rootCommand.Flags().BoolP("version", "v", false, i18n.T("Version for Skopeo"))
+	rootCommand.PersistentFlags().BoolVar(&opts.debug, "debug", false, i18n.T("enable debug output"))
+	rootCommand.PersistentFlags().StringVar(&opts.policyPath, "policy", "", i18n.T("Path to a trust policy file"))
+	rootCommand.PersistentFlags().BoolVar(&opts.insecurePolicy, "insecure-policy", false, i18n.T("run the tool without any policy check"))
+	rootCommand.PersistentFlags().StringVar(&opts.registriesDirPath, "registries.d", "", i18n.T("use registry configuration files in `DIR` (e.g. for container signature storage)"))
+	rootCommand.PersistentFlags().StringVar(&opts.overrideArch, "override-arch", "", i18n.T("use `ARCH` instead of the architecture of the machine for choosing images"))
+	rootCommand.PersistentFlags().StringVar(&opts.overrideOS, "override-os", "", i18n.T("use `OS` instead of the running OS for choosing images"))
+	rootCommand.PersistentFlags().StringVar(&opts.overrideVariant, "override-variant", "", i18n.T("use `VARIANT` instead of the running architecture variant for choosing images"))
+	rootCommand.PersistentFlags().DurationVar(&opts.commandTimeout, "command-timeout", 0, i18n.T("timeout for the command execution"))
+	rootCommand.PersistentFlags().StringVar(&opts.tmpDir, "tmpdir", "", i18n.T("directory used to store temporary files"))
+	rootCommand.PersistentFlags().StringVar(&opts.errorFormat, "error-format", errorFormatText, i18n.T("report a failure as `FORMAT`, text or json (an object with the category, exit code and message, on stderr)"))
+	rootCommand.PersistentFlags().StringVar(&opts.configPath, "config", "", i18n.T("read default options and registry settings from the configuration file at `PATH` (default ~/.config/gopull/config.yaml)"))
+	rootCommand.PersistentFlags().StringVar(&opts.profile, "profile", "", i18n.T("use the options of profile `NAME` in the configuration file"))
+	rootCommand.PersistentFlags().StringVar(&opts.shortNameMode, "short-name-mode", "", i18n.T("resolve ambiguous short names in `MODE`: enforcing (prompt, fail without a terminal), permissive (prompt, else try all registries) or disabled (try all registries); default from registries.conf"))
+	rootCommand.PersistentFlags().BoolVar(&opts.noDockerHubDefault, "no-docker-hub-default", false, i18n.T("fail for short names which registries.conf does not resolve, instead of using Docker Hub"))
+	rootCommand.PersistentFlags().StringVar(&opts.lang, "lang", "", i18n.T("language of messages, en or zh (default from LC_ALL, LC_MESSAGES or LANG)"))
+	bindEnvironment(rootCommand.PersistentFlags())
+	flag := commonFlag.OptionalBoolFlag(rootCommand.Flags(), &opts.tlsVerify, "tls-verify", i18n.T("Require HTTPS and verify certificates when accessing the registry"))
 	flag.Hidden = true
 	rootCommand.AddCommand(
 		download(&opts),
 		pull(&opts),
 		push(&opts),
 		inspectCmd(&opts),
+		historyCmd(&opts),
+		exportCmd(&opts),
+		catCmd(&opts),
+		cpCmd(&opts),
+		lsCmd(&opts),
+		sbomCmd(&opts),
+		policyCmd(&opts),
 		loginCmd(&opts),
 		logoutCmd(&opts),
 	)
@@ -76,9 +93,28 @@ func createApp() (*cobra.Command, *globalOptions) {
 
 // before is run by the cli package for any command, before running the command-specific handler.
 func (opts *globalOptions) before(cmd *cobra.Command, args []string) error {
+	opts.ctx = cmd.Context()
+	// Options are set on the command line, else in the environment, else in the configuration file.
+	if err := applyEnvironment(cmd); err != nil {
+		return err
+	}
+	if err := opts.loadConfig(cmd); err != nil {
+		return err
+	}
 	if opts.debug {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
+	if opts.errorFormat != errorFormatText && opts.errorFormat != errorFormatJSON {
+		return i18n.Errorf("unknown error format %q, expected %s or %s", opts.errorFormat, errorFormatText, errorFormatJSON)
+	}
+	if err := checkShortNameMode(opts.shortNameMode); err != nil {
+		return err
+	}
+	if opts.lang != "" {
+		if _, err := i18n.Parse(opts.lang); err != nil {
+			return err
+		}
+	}
 	if opts.tlsVerify.Present() {
 		logrus.Warn("'--tls-verify' is deprecated, please set this on the specific subcommand")
 	}
@@ -89,12 +125,41 @@ func Execute() {
 	if reexec.Init() {
 		return
 	}
-	rootCmd, _ := createApp()
-	if err := rootCmd.Execute(); err != nil {
-		if isNotFoundImageError(err) {
-			logrus.StandardLogger().Log(logrus.FatalLevel, err)
-			logrus.Exit(2)
+	i18n.SetLanguage(languageFromArgs(os.Args[1:]))
+	rootCmd, opts := createApp()
+	if err := rootCmd.ExecuteContext(handleInterrupts()); err != nil {
+		logrus.Exit(reportError(os.Stderr, err, opts.errorFormat))
+	}
+}
+
+// languageFromArgs returns the language selected by a --lang option in args, or by the environment:
+// GOPULL_LANG, else the locale.
+// Help strings are translated when the commands are created, so the language must be known before parsing args;
+// an invalid --lang value is ignored here, and reported by globalOptions.before.
+func languageFromArgs(args []string) string {
+	for i, arg := range args {
+		var value string
+		switch {
+		case arg == "--":
+			return languageFromEnvironment()
+		case arg == "--lang" && i+1 < len(args):
+			value = args[i+1]
+		case strings.HasPrefix(arg, "--lang="):
+			value = strings.TrimPrefix(arg, "--lang=")
+		default:
+			continue
+		}
+		if lang, err := i18n.Parse(value); err == nil {
+			return lang
 		}
-		logrus.Fatal(err)
+		break
+	}
+	return languageFromEnvironment()
+}
+
+func languageFromEnvironment() string {
+	if lang, err := i18n.Parse(os.Getenv(envName("lang"))); err == nil {
+		return lang
 	}
+	return i18n.Detect()
 }
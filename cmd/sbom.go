@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/transports"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
+	"gopull/pkgs/rootfs"
+	"gopull/pkgs/sbom"
+)
+
+type sbomOptions struct {
+	global    *globalOptions
+	image     *imageOptions
+	retryOpts *retry.Options
+	format    string // One of the sbom.Format* values
+	output    string // File to write the document to, instead of standard output
+}
+
+func sbomCmd(global *globalOptions) *cobra.Command {
+	sharedFlags, sharedOpts := sharedImageFlags()
+	imageFlags, imageOpts := imageFlags(global, sharedOpts, nil, "", "")
+	retryFlags, retryOpts := retryFlags()
+	opts := sbomOptions{
+		global:    global,
+		image:     imageOpts,
+		retryOpts: retryOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "sbom [command options] IMAGE-NAME",
+		Short: i18n.T("Generate a software bill of materials for image IMAGE-NAME"),
+		Long: fmt.Sprintf(i18n.T(`Generate a software bill of materials for "IMAGE-NAME", by reading the package
+databases and lock files in its layers; no vulnerability scanner or other network
+service is used.
+
+Recognized sources:
+  dpkg (/var/lib/dpkg/status, status.d), apk (/lib/apk/db/installed),
+  rpm (rpmdb.sqlite), Go build information in executables,
+  npm (package-lock.json, yarn.lock), Python (Pipfile.lock, poetry.lock,
+  pinned requirements*.txt, installed *.dist-info/METADATA)
+
+"IMAGE-NAME" may be a registry reference, a "transport:details" name, or a path
+to a docker-archive or oci-archive file or an OCI layout directory.
+
+Supported transports:
+%s
+`), strings.Join(transports.ListNames(), ", ")),
+		RunE: commandAction(opts.run),
+		Example: `gopull sbom alpine
+gopull sbom --format cyclonedx -o redis.cdx.json redis:7
+gopull sbom --format spdx-json -o app.spdx.json app.tar`,
+		ValidArgsFunction: autocompleteSupportedTransports,
+	}
+	adjustUsage(cmd)
+	flags := cmd.Flags()
+	flags.StringVar(&opts.format, "format", sbom.FormatSPDXJSON, fmt.Sprintf(i18n.T("Document `FORMAT`, %s or %s"), sbom.FormatSPDXJSON, sbom.FormatCycloneDX))
+	flags.StringVarP(&opts.output, "output", "o", "", i18n.T("Write the document to `FILE` instead of standard output"))
+	flags.AddFlagSet(&sharedFlags)
+	flags.AddFlagSet(&imageFlags)
+	flags.AddFlagSet(&retryFlags)
+	return cmd
+}
+
+func (opts *sbomOptions) run(args []string, stdout io.Writer) (retErr error) {
+	if len(args) != 1 {
+		return errorShouldDisplayUsage{i18n.New("Exactly one argument expected")}
+	}
+	// Fail before reading all layers if the format is wrong.
+	if err := sbom.CheckFormat(opts.format); err != nil {
+		return err
+	}
+	ctx, cancel := opts.global.commandTimeoutContext()
+	defer cancel()
+
+	src, img, err := openSourceImage(ctx, opts.image, args[0], opts.retryOpts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing image", err)
+		}
+	}()
+
+	var rawManifest []byte
+	if err := retry.IfNecessary(ctx, func() error {
+		rawManifest, _, err = img.Manifest(ctx)
+		return err
+	}, opts.retryOpts); err != nil {
+		return i18n.Errorf("Error retrieving manifest for image: %w", err)
+	}
+	manifestDigest, err := manifest.Digest(rawManifest)
+	if err != nil {
+		return i18n.Errorf("Error computing manifest digest: %w", err)
+	}
+
+	// The temporary files of the collector are kept in a directory of their own, so that they are removed
+	// even if gopull exits while one of them is in use.
+	tmpDir, err := os.MkdirTemp(opts.global.tmpDir, "gopull-sbom")
+	if err != nil {
+		return err
+	}
+	removeTmpDir := func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			logrus.Warnf("Removing %s: %v", tmpDir, err)
+		}
+	}
+	defer removeTmpDir()
+	defer atInterrupt(removeTmpDir)()
+
+	collector := sbom.NewCollector(tmpDir)
+	if err := walkLayersTopDown(ctx, src, img.LayerInfos(), opts.retryOpts, rootfs.NewMerger(), func(entry *rootfs.Entry, r io.Reader) error {
+		hdr := entry.Header
+		// Hard links are skipped, the file they link to is read instead.
+		if entry.Shadowed || hdr.Typeflag != tar.TypeReg || !sbom.Wants(hdr.Name, hdr.FileInfo().Mode(), hdr.Size) {
+			return nil
+		}
+		// A single unreadable database should not prevent reporting everything else.
+		if err := collector.Add(hdr.Name, r); err != nil {
+			logrus.Warnf("Ignoring unreadable file: %v", err)
+		}
+		return nil
+	}, nil); err != nil {
+		return err
+	}
+
+	doc := sbom.Document{
+		Name:     args[0],
+		Digest:   manifestDigest.String(),
+		Created:  time.Now(),
+		Tool:     "gopull " + version,
+		Distro:   collector.Distro(),
+		Packages: collector.Packages(),
+	}
+	if opts.output == "" {
+		return doc.Write(stdout, opts.format)
+	}
+	f, err := os.Create(opts.output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+		if retErr != nil {
+			os.Remove(opts.output)
+		}
+	}()
+	if err := doc.Write(f, opts.format); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, i18n.T("Wrote %d packages to %s\n"), len(doc.Packages), opts.output)
+	return nil
+}
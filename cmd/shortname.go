@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/containers/image/v5/pkg/shortnames"
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/containers/image/v5/types"
+	"github.com/sirupsen/logrus"
+
+	"gopull/pkgs/i18n"
+)
+
+// shortNameModes are the values of --short-name-mode.
+var shortNameModes = map[string]types.ShortNameMode{
+	"enforcing":  types.ShortNameModeEnforcing,
+	"permissive": types.ShortNameModePermissive,
+	"disabled":   types.ShortNameModeDisabled,
+}
+
+// checkShortNameMode returns an error if mode is not a valid --short-name-mode value.
+func checkShortNameMode(mode string) error {
+	if _, ok := shortNameModes[mode]; mode != "" && !ok {
+		return i18n.Errorf("unknown short-name mode %q, expected enforcing, permissive or disabled", mode)
+	}
+	return nil
+}
+
+// resolvedName is an image name resolved to the names it may refer to.
+type resolvedName struct {
+	candidates []string             // Names to try, in order
+	resolved   *shortnames.Resolved // The resolution through registries.conf, or nil if name was used as is
+}
+
+// resolveShortName resolves name, a registry image name, the way Podman does: a short name like "redis" is resolved
+// through the short-name aliases and the unqualified-search registries of registries.conf, prompting for a choice
+// when it is ambiguous if the short-name mode requires it.
+// If registries.conf defines neither for name, it refers to Docker Hub, unless --no-docker-hub-default.
+func (opts *globalOptions) resolveShortName(name string) (*resolvedName, error) {
+	if !shortnames.IsShortName(name) {
+		return &resolvedName{candidates: []string{name}}, nil
+	}
+	sys := opts.newSystemContext()
+	resolved, err := shortnames.Resolve(sys, name)
+	if err != nil {
+		registries, usrErr := sysregistriesv2.UnqualifiedSearchRegistries(sys)
+		if usrErr != nil || len(registries) != 0 || opts.noDockerHubDefault {
+			return nil, invalidReferenceError{i18n.Errorf("resolving short name %q: %w", name, err)}
+		}
+		logrus.Debugf("Short name %q refers to Docker Hub: %v", name, err)
+		return &resolvedName{candidates: []string{name}}, nil
+	}
+	if description := resolved.Description(); description != "" {
+		logrus.Debug(description)
+	}
+	res := &resolvedName{resolved: resolved}
+	for _, candidate := range resolved.PullCandidates {
+		res.candidates = append(res.candidates, candidate.Value.String())
+	}
+	return res, nil
+}
+
+// resolveSourceImageName is resolveShortName for imageName, an image name as returned by sourceImageName;
+// only registry names are resolved.
+func (opts *globalOptions) resolveSourceImageName(imageName string) (*resolvedName, error) {
+	name, ok := strings.CutPrefix(imageName, "docker://")
+	if !ok {
+		return &resolvedName{candidates: []string{imageName}}, nil
+	}
+	res, err := opts.resolveShortName(name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range res.candidates {
+		res.candidates[i] = "docker://" + res.candidates[i]
+	}
+	return res, nil
+}
+
+// try calls fn with the candidates of r in order, until it succeeds; the candidate used is then recorded as
+// a short-name alias, if registries.conf asks for it.
+func (r *resolvedName) try(fn func(name string) error) error {
+	var errs []error
+	for i, candidate := range r.candidates {
+		err := fn(candidate)
+		if err == nil {
+			if r.resolved != nil {
+				if err := r.resolved.PullCandidates[i].Record(); err != nil {
+					logrus.Warnf("%v", err)
+				}
+			}
+			return nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		errs = append(errs, err)
+		if i+1 < len(r.candidates) {
+			logrus.Debugf("Using %s failed, trying %s: %v", candidate, r.candidates[i+1], err)
+		}
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return candidatesError{message: r.resolved.FormatPullErrors(errs), errs: errs}
+}
+
+// candidatesError is the failure of all candidates of a short name.
+type candidatesError struct {
+	message error
+	errs    []error
+}
+
+func (e candidatesError) Error() string {
+	return e.message.Error()
+}
+
+func (e candidatesError) Unwrap() []error {
+	return e.errs
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/image/v5/directory"
+	"github.com/containers/image/v5/types"
+
+	"gopull/pkgs/image"
+)
+
+// writeRegistriesConf writes a registries.conf with content to a temporary directory, and returns its path.
+// HOME is moved to a temporary directory, so that the short-name aliases of the user are not used.
+func writeRegistriesConf(t *testing.T, content string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	path := filepath.Join(t.TempDir(), "registries.conf")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveShortName(t *testing.T) {
+	for _, c := range []struct {
+		name, conf         string
+		noDockerHubDefault bool
+		expected           []string // nil if an error is expected
+	}{
+		{"redis", `unqualified-search-registries = ["harbor.internal"]`, false, []string{"harbor.internal/redis:latest"}},
+		{"redis:7", `unqualified-search-registries = ["harbor.internal", "quay.io"]
+short-name-mode = "disabled"`, false, []string{"harbor.internal/redis:7", "quay.io/redis:7"}},
+		{"redis", `[aliases]
+"redis" = "registry.example.com/library/redis"`, false, []string{"registry.example.com/library/redis:latest"}},
+		{"quay.io/app/redis", `unqualified-search-registries = ["harbor.internal"]`, false, []string{"quay.io/app/redis"}},
+		{"redis", ``, false, []string{"redis"}},
+		{"redis", ``, true, nil},
+	} {
+		global := &globalOptions{
+			registriesConfPath: writeRegistriesConf(t, c.conf),
+			noDockerHubDefault: c.noDockerHubDefault,
+		}
+		res, err := global.resolveShortName(c.name)
+		if c.expected == nil {
+			var invalidRef invalidReferenceError
+			if !errors.As(err, &invalidRef) {
+				t.Errorf("%q with %q: expected an invalid reference error, got %v", c.name, c.conf, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q with %q: %v", c.name, c.conf, err)
+			continue
+		}
+		if len(res.candidates) != len(c.expected) {
+			t.Errorf("%q with %q: candidates %v, expected %v", c.name, c.conf, res.candidates, c.expected)
+			continue
+		}
+		for i := range c.expected {
+			if res.candidates[i] != c.expected[i] {
+				t.Errorf("%q with %q: candidates %v, expected %v", c.name, c.conf, res.candidates, c.expected)
+				break
+			}
+		}
+	}
+}
+
+// TestExecCopyDestinationOfShortName checks that the destination of a short name resolved to another registry than
+// Docker Hub is built for that registry.
+func TestExecCopyDestinationOfShortName(t *testing.T) {
+	global := &globalOptions{
+		registriesConfPath: writeRegistriesConf(t, `unqualified-search-registries = ["harbor.internal"]`),
+		insecurePolicy:     true,
+	}
+	opts := &copyOptions{
+		global:              global,
+		deprecatedTLSVerify: &deprecatedTLSVerifyOption{},
+		progress:            progressText,
+		resolveShortNames:   true,
+	}
+	var srcNames, destNames []string
+	buildSrc := func(name string) (types.ImageReference, *types.SystemContext, error) {
+		srcNames = append(srcNames, name)
+		ref, err := directory.NewReference(t.TempDir())
+		return ref, &types.SystemContext{}, err
+	}
+	errStop := errors.New("destination built")
+	buildDest := func(name string) (types.ImageReference, *types.SystemContext, error) {
+		destNames = append(destNames, name)
+		return nil, nil, errStop
+	}
+	if err := opts.execCopy([]string{"redis"}, io.Discard, buildSrc, buildDest); !errors.Is(err, errStop) {
+		t.Fatalf("expected the copy to stop after building the destination, got %v", err)
+	}
+	if len(srcNames) != 1 || srcNames[0] != "harbor.internal/redis:latest" {
+		t.Errorf("source built for %v, expected harbor.internal/redis:latest", srcNames)
+	}
+	if len(destNames) != 1 || destNames[0] != "harbor.internal/redis:latest" {
+		t.Fatalf("destination built for %v, expected harbor.internal/redis:latest", destNames)
+	}
+
+	parsed, err := image.ParseImageStr(destNames[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Registry != "harbor.internal" {
+		t.Errorf("registry of the destination %q is %q, expected harbor.internal", destNames[0], parsed.Registry)
+	}
+	tag, err := buildDestTag(parsed, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "harbor.internal/redis:latest" {
+		t.Errorf("destination tag %q, expected harbor.internal/redis:latest", tag)
+	}
+}
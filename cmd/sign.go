@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/containers/common/pkg/password"
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/pkg/cli"
+	"github.com/distribution/reference"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"gopull/pkgs/i18n"
+)
+
+// signOptions collects CLI flags for signing the destination image while copying.
+// Signatures are written to the registry as sigstore attachments, or to the lookaside storage configured in registries.d.
+type signOptions struct {
+	signByFingerprint        string // Sign the image using a GPG key with the specified fingerprint
+	signBySigstorePrivateKey string // Sign the image using a sigstore private key
+	signPassphraseFile       string // Path pointing to a passphrase file when signing
+	signIdentity             string // Identity of the signed image, must be a fully specified docker reference
+}
+
+// signFlags prepares a collection of CLI flags writing into signOptions, and the managed signOptions structure.
+func signFlags() (pflag.FlagSet, *signOptions) {
+	opts := signOptions{}
+	fs := pflag.FlagSet{}
+	fs.StringVar(&opts.signByFingerprint, "sign-by", "", i18n.T("Sign the image using a GPG key with the specified `FINGERPRINT`"))
+	fs.StringVar(&opts.signBySigstorePrivateKey, "sign-by-sigstore-private-key", "", i18n.T("Sign the image using a sigstore private key at `PATH`"))
+	fs.StringVar(&opts.signPassphraseFile, "sign-passphrase-file", "", i18n.T("Read a passphrase for signing an image from `PATH`"))
+	fs.StringVar(&opts.signIdentity, "sign-identity", "", i18n.T("Identity of signed image, must be a fully specified docker reference. Defaults to the target docker reference."))
+	return fs, &opts
+}
+
+// apply sets the signing options of options.
+func (opts *signOptions) apply(options *copy.Options, stdin io.Reader, stdout io.Writer) error {
+	if opts.signPassphraseFile != "" && opts.signByFingerprint != "" && opts.signBySigstorePrivateKey != "" {
+		return i18n.New("Only one of --sign-by and --sign-by-sigstore-private-key can be used with --sign-passphrase-file")
+	}
+	var passphrase string
+	if opts.signPassphraseFile != "" {
+		p, err := cli.ReadPassphraseFile(opts.signPassphraseFile)
+		if err != nil {
+			return err
+		}
+		passphrase = p
+	} else if opts.signBySigstorePrivateKey != "" {
+		p, err := promptForPassphrase(opts.signBySigstorePrivateKey, stdin, stdout)
+		if err != nil {
+			return err
+		}
+		passphrase = p
+	} // opts.signByFingerprint triggers a GPG-agent passphrase prompt, possibly using a more secure channel, so we usually shouldn’t prompt ourselves if no passphrase was explicitly provided.
+
+	if opts.signIdentity != "" {
+		signIdentity, err := reference.ParseNamed(opts.signIdentity)
+		if err != nil {
+			return i18n.Errorf("Could not parse --sign-identity: %v", err)
+		}
+		options.SignIdentity = signIdentity
+	}
+	options.SignBy = opts.signByFingerprint
+	options.SignPassphrase = passphrase
+	options.SignBySigstorePrivateKeyFile = opts.signBySigstorePrivateKey
+	options.SignSigstorePrivateKeyPassphrase = []byte(passphrase)
+	return nil
+}
+
+// promptForPassphrase interactively prompts for a passphrase related to privateKeyFile
+func promptForPassphrase(privateKeyFile string, stdin io.Reader, stdout io.Writer) (string, error) {
+	stdinFile, ok := stdin.(*os.File)
+	if !ok {
+		return "", i18n.Errorf("Cannot prompt for a passphrase for key %s, not reading from a terminal", privateKeyFile)
+	}
+	if !term.IsTerminal(int(stdinFile.Fd())) {
+		return "", i18n.Errorf("Cannot prompt for a passphrase for key %s, stdin is not a terminal; use --sign-passphrase-file", privateKeyFile)
+	}
+	fmt.Fprintf(stdout, i18n.T("Passphrase for key %s: "), privateKeyFile)
+	passphrase, err := password.Read(int(stdinFile.Fd()))
+	if err != nil {
+		return "", i18n.Errorf("Error reading password: %w", err)
+	}
+	fmt.Fprintf(stdout, "\n")
+	return string(passphrase), nil
+}
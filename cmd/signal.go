@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"gopull/pkgs/i18n"
+)
+
+var (
+	interruptLock     sync.Mutex
+	interruptCleanups = map[int]func(){} // Run when gopull exits on a second signal, indexed by registration
+	interruptNext     int
+)
+
+// interruptedError is the cause of the cancellation of the root context when gopull receives SIGINT or SIGTERM.
+// It is a context.Canceled error.
+type interruptedError struct{}
+
+func (interruptedError) Error() string {
+	return i18n.T("interrupted")
+}
+
+func (interruptedError) Unwrap() error {
+	return context.Canceled
+}
+
+// handleInterrupts returns a context which is cancelled when gopull receives SIGINT or SIGTERM, so that the command
+// stops, removes its temporary files and reports what was not done.
+// On a second signal, the functions registered with atInterrupt are called and gopull exits immediately.
+func handleInterrupts() context.Context {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logrus.Warnf("Received %v, stopping; repeat to exit immediately", sig)
+		cancel(interruptedError{})
+		sig = <-signals
+		interruptLock.Lock() // Never unlocked, cleanups in progress can't be unregistered.
+		for _, fn := range interruptCleanups {
+			fn()
+		}
+		logrus.Warnf("Received %v again, exiting", sig)
+		os.Exit(errorCategoryInterrupted.ExitCode)
+	}()
+	return ctx
+}
+
+// interrupted returns true if err is the failure of an operation using ctx, because gopull was interrupted.
+func interrupted(ctx context.Context, err error) bool {
+	var interruptedErr interruptedError
+	return err != nil && errors.Is(err, context.Canceled) && errors.As(context.Cause(ctx), &interruptedErr)
+}
+
+// atInterrupt arranges for cleanup to be called if gopull exits without returning from the command,
+// on a second SIGINT or SIGTERM, until the returned function is called.
+func atInterrupt(cleanup func()) func() {
+	interruptLock.Lock()
+	defer interruptLock.Unlock()
+	id := interruptNext
+	interruptNext++
+	interruptCleanups[id] = cleanup
+	return func() {
+		interruptLock.Lock()
+		defer interruptLock.Unlock()
+		delete(interruptCleanups, id)
+	}
+}
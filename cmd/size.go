@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/containers/image/v5/types"
+	digest "github.com/opencontainers/go-digest"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/progress"
+)
+
+// estimatedCompressionRatio approximates how much a compressed layer grows when it is extracted.
+// Manifests only record compressed sizes, so uncompressed sizes are always estimates.
+const estimatedCompressionRatio = 2.5
+
+// layerSize describes the size of a single layer.
+type layerSize struct {
+	Digest                         digest.Digest
+	MIMEType                       string
+	Size                           int64 // Compressed size, as transferred; -1 if unknown
+	SizeHuman                      string
+	EstimatedUncompressedSize      int64 // Estimated size after extraction; -1 if unknown
+	EstimatedUncompressedSizeHuman string
+}
+
+// imageSize summarizes the sizes of all layers of an image.
+type imageSize struct {
+	CompressedSize                 int64
+	CompressedSizeHuman            string
+	EstimatedUncompressedSize      int64
+	EstimatedUncompressedSizeHuman string
+	LayerSizes                     []layerSize
+}
+
+// newImageSize sums up the layer sizes recorded in an image manifest, as returned by types.Image.LayerInfos.
+// Layers with an unknown size (e.g. in schema1 manifests) are left out of the totals.
+func newImageSize(layers []types.BlobInfo) imageSize {
+	res := imageSize{LayerSizes: make([]layerSize, 0, len(layers))}
+	for _, layer := range layers {
+		ls := layerSize{
+			Digest:                    layer.Digest,
+			MIMEType:                  layer.MediaType,
+			Size:                      layer.Size,
+			EstimatedUncompressedSize: estimateUncompressedSize(layer.MediaType, layer.Size),
+		}
+		ls.SizeHuman = progress.HumanSize(ls.Size)
+		ls.EstimatedUncompressedSizeHuman = progress.HumanSize(ls.EstimatedUncompressedSize)
+		if ls.Size > 0 {
+			res.CompressedSize += ls.Size
+			res.EstimatedUncompressedSize += ls.EstimatedUncompressedSize
+		}
+		res.LayerSizes = append(res.LayerSizes, ls)
+	}
+	res.CompressedSizeHuman = progress.HumanSize(res.CompressedSize)
+	res.EstimatedUncompressedSizeHuman = progress.HumanSize(res.EstimatedUncompressedSize)
+	return res
+}
+
+// estimateUncompressedSize returns the expected extracted size of a layer with mimeType and compressed size.
+func estimateUncompressedSize(mimeType string, size int64) int64 {
+	if size < 0 {
+		return -1
+	}
+	// Both application/vnd.oci.image.layer.v1.tar and application/vnd.docker.image.rootfs.diff.tar
+	// are not compressed; everything else (+gzip, +zstd, .tar.gzip, …) is assumed to be.
+	if strings.HasSuffix(mimeType, ".tar") {
+		return size
+	}
+	return int64(float64(size) * estimatedCompressionRatio)
+}
+
+// writeReport writes a human-readable per-layer breakdown and the totals to w.
+func (s imageSize) writeReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("LAYER\tSIZE\tUNCOMPRESSED (EST.)"))
+	for _, layer := range s.LayerSizes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", layer.Digest, layer.SizeHuman, layer.EstimatedUncompressedSizeHuman)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, i18n.T("Total download size: %s\n"), s.CompressedSizeHuman)
+	_, err := fmt.Fprintf(w, i18n.T("Estimated size on disk: %s\n"), s.EstimatedUncompressedSizeHuman)
+	return err
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/pkg/compression"
+	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
+)
+
+// explicitSourceTransports lists the transports which may be given as an explicit
+// "transport:" prefix for commands which read an image, e.g. inspect.
+var explicitSourceTransports = []string{"docker", "docker-archive", "oci-archive", "oci", "docker-daemon", "dir"}
+
+// sourceImageName converts an IMAGE argument to a transport-qualified name understood by alltransports.ParseImageName.
+// Names with an explicit transport prefix are returned unchanged, paths to existing image archives or layouts
+// are detected, and everything else is treated as a registry reference.
+func sourceImageName(arg string) (string, error) {
+	if transport, _, ok := strings.Cut(arg, ":"); ok && slices.Contains(explicitSourceTransports, transport) {
+		// "docker:tag" is an image named docker, not the docker transport, which is always "docker://".
+		if transport != "docker" || strings.HasPrefix(arg, "docker://") {
+			return arg, nil
+		}
+	}
+
+	fi, err := os.Stat(arg)
+	if err != nil {
+		return "docker://" + arg, nil
+	}
+	name, err := localImageName(arg, fi)
+	if err != nil {
+		if looksLikePath(arg) {
+			return "", err
+		}
+		// Something unrelated that happens to share the name of the image, e.g. a "redis" directory.
+		return "docker://" + arg, nil
+	}
+	return name, nil
+}
+
+// looksLikePath returns true if arg is clearly meant as a local path rather than an image name.
+func looksLikePath(arg string) bool {
+	if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") {
+		return true
+	}
+	for _, suffix := range []string{".tar", ".tar.gz", ".tgz", ".tar.zst"} {
+		if strings.HasSuffix(arg, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// localImageName returns a transport-qualified name for the image archive or layout at path.
+func localImageName(path string, fi os.FileInfo) (string, error) {
+	if fi.IsDir() {
+		if _, err := os.Stat(filepath.Join(path, "oci-layout")); err == nil {
+			return "oci:" + path, nil
+		}
+		if _, err := os.Stat(filepath.Join(path, "manifest.json")); err == nil {
+			return "dir:" + path, nil
+		}
+		return "", i18n.Errorf("%s is neither an OCI layout nor a dir: image", path)
+	}
+	transport, err := detectArchiveTransport(path)
+	if err != nil {
+		return "", err
+	}
+	return transport + ":" + path, nil
+}
+
+// detectArchiveTransport determines whether the (possibly compressed) tar file at path
+// is a docker-archive: or an oci-archive: image.
+// Only the entries before the top-level metadata are read: the contents of uncompressed archives are skipped
+// by seeking, and the scan stops once both oci-layout and index.json were seen.
+func detectArchiveTransport(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	stream, compressed, err := compression.AutoDecompress(f)
+	if err != nil {
+		return "", i18n.Errorf("reading %s: %w", path, err)
+	}
+	defer stream.Close()
+	var r io.Reader = stream
+	if !compressed {
+		// tar.Reader seeks past the contents of entries when it can.
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return "", i18n.Errorf("reading %s: %w", path, err)
+		}
+		r = f
+	}
+
+	hasOCILayout, hasIndex := false, false
+	tr := tar.NewReader(r)
+	for !hasOCILayout || !hasIndex {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", i18n.Errorf("%s is not an image archive: %w", path, err)
+		}
+		switch filepath.Clean(hdr.Name) {
+		case "manifest.json":
+			// Archives written by (docker save) since Docker 25 contain both, manifest.json before oci-layout;
+			// docker-archive: reads them best.
+			return "docker-archive", nil
+		case "oci-layout":
+			hasOCILayout = true
+		case "index.json":
+			hasIndex = true
+		}
+	}
+	if hasOCILayout {
+		return "oci-archive", nil
+	}
+	return "", i18n.Errorf("%s is neither a docker-archive nor an oci-archive image", path)
+}
+
+// openImage opens ref for reading and returns its ImageSource together with the image chosen for sys;
+// manifest lists are resolved transparently.
+// The caller must call .Close() on the returned ImageSource.
+func openImage(ctx context.Context, sys *types.SystemContext, ref types.ImageReference, retryOpts *retry.Options) (types.ImageSource, types.Image, error) {
+	var src types.ImageSource
+	if err := retry.IfNecessary(ctx, func() error {
+		var err error
+		src, err = ref.NewImageSource(ctx, sys)
+		return err
+	}, retryOpts); err != nil {
+		return nil, nil, i18n.Errorf("Error opening image %q: %w", transports.ImageName(ref), err)
+	}
+	var img types.Image
+	if err := retry.IfNecessary(ctx, func() error {
+		var err error
+		img, err = image.FromUnparsedImage(ctx, sys, image.UnparsedInstance(src, nil))
+		return err
+	}, retryOpts); err != nil {
+		if closeErr := src.Close(); closeErr != nil {
+			err = noteCloseFailure(err, "closing image", closeErr)
+		}
+		return nil, nil, i18n.Errorf("Error parsing manifest for image: %w", err)
+	}
+	return src, img, nil
+}
+
+// openSourceImage resolves the IMAGE argument arg as sourceImageName and resolveSourceImageName do, and opens it using opts.
+// The caller must call .Close() on the returned ImageSource.
+func openSourceImage(ctx context.Context, opts *imageOptions, arg string, retryOpts *retry.Options) (types.ImageSource, types.Image, error) {
+	arg, err := opts.global.expandAlias(arg)
+	if err != nil {
+		return nil, nil, err
+	}
+	imageName, err := sourceImageName(arg)
+	if err != nil {
+		return nil, nil, err
+	}
+	imageNames, err := opts.global.resolveSourceImageName(imageName)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		src types.ImageSource
+		img types.Image
+	)
+	err = imageNames.try(func(imageName string) error {
+		ref, err := alltransports.ParseImageName(imageName)
+		if err != nil {
+			return invalidReferenceError{i18n.Errorf("Error parsing image name %q: %w", imageName, err)}
+		}
+		ref, err = opts.global.mirrorReference(ref)
+		if err != nil {
+			return err
+		}
+		sys, err := opts.newSystemContextFor(ref)
+		if err != nil {
+			return err
+		}
+		src, img, err = openImage(ctx, sys, ref, retryOpts)
+		return err
+	})
+	return src, img, err
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestArchive writes a tar file at path containing empty files named names, gzip-compressed if compress.
+func writeTestArchive(t *testing.T, path string, compress bool, names ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var w io.Writer = f
+	if compress {
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetectArchiveTransport(t *testing.T) {
+	dir := t.TempDir()
+	for _, c := range []struct {
+		name     string
+		compress bool
+		entries  []string
+		expected string // "ERROR: TEXT" if an error containing TEXT is expected
+	}{
+		{"docker.tar", false, []string{"0123abcd.json", "manifest.json", "repositories"}, "docker-archive"},
+		{"oci.tar", false, []string{"blobs/sha256/0123abcd", "index.json", "oci-layout"}, "oci-archive"},
+		{"both.tar", false, []string{"blobs/sha256/0123abcd", "index.json", "manifest.json", "oci-layout", "repositories"}, "docker-archive"}, // docker save since Docker 25
+		{"oci-first.tar", false, []string{"oci-layout", "index.json", "manifest.json"}, "oci-archive"},                                        // Not scanned past the OCI metadata
+		{"dotted.tar", false, []string{"./oci-layout", "./index.json"}, "oci-archive"},
+		{"compressed.tar.gz", true, []string{"manifest.json"}, "docker-archive"},
+		{"other.tar", false, []string{"etc/hostname"}, "ERROR: is neither a docker-archive nor an oci-archive image"},
+	} {
+		path := filepath.Join(dir, c.name)
+		writeTestArchive(t, path, c.compress, c.entries...)
+		res, err := detectArchiveTransport(path)
+		if err != nil {
+			res = "ERROR: " + err.Error()
+		}
+		if expectedErr, ok := strings.CutPrefix(c.expected, "ERROR: "); ok && strings.Contains(res, expectedErr) {
+			continue
+		}
+		if res != c.expected {
+			t.Errorf("%s: %q, expected %q", c.name, res, c.expected)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "text.tar"), []byte("not a tar file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := detectArchiveTransport(filepath.Join(dir, "text.tar")); err == nil {
+		t.Errorf("text file: %q, expected an error", res)
+	}
+}
+
+func TestSourceImageName(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	writeTestArchive(t, "image.tar", false, "manifest.json")
+	writeTestArchive(t, "oci.tar", false, "oci-layout", "index.json")
+	writeTestArchive(t, "other.tar", false, "etc/hostname")
+	for _, d := range []string{"layout", "dirimage", "redis", "empty"} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"layout/oci-layout", "dirimage/manifest.json", "redis/dump.rdb"} {
+		if err := os.WriteFile(f, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []struct {
+		arg, expected string // expected is "ERROR: TEXT" if an error containing TEXT is expected
+	}{
+		// Explicit transports
+		{"docker://alpine", "docker://alpine"},
+		{"docker-archive:image.tar", "docker-archive:image.tar"},
+		{"oci-archive:missing.tar", "oci-archive:missing.tar"},
+		{"oci:layout:latest", "oci:layout:latest"},
+		{"dir:somewhere", "dir:somewhere"},
+		{"docker-daemon:alpine:latest", "docker-daemon:alpine:latest"},
+		// An image named docker, not the docker transport
+		{"docker:latest", "docker://docker:latest"},
+		{"docker", "docker://docker"},
+		// Registry references
+		{"alpine", "docker://alpine"},
+		{"quay.io/prometheus/node-exporter:v1.8.0", "docker://quay.io/prometheus/node-exporter:v1.8.0"},
+		{"localhost:5000/app", "docker://localhost:5000/app"},
+		// Local archives and layouts
+		{"image.tar", "docker-archive:image.tar"},
+		{"oci.tar", "oci-archive:oci.tar"},
+		{"./layout", "oci:./layout"},
+		{"dirimage", "dir:dirimage"},
+		{"other.tar", "ERROR: is neither a docker-archive nor an oci-archive image"},
+		{"./empty", "ERROR: is neither an OCI layout nor a dir: image"},
+		// An unrelated directory named like an image
+		{"redis", "docker://redis"},
+		{"empty", "docker://empty"},
+	} {
+		res, err := sourceImageName(c.arg)
+		if err != nil {
+			res = "ERROR: " + err.Error()
+		}
+		if expectedErr, ok := strings.CutPrefix(c.expected, "ERROR: "); ok && strings.Contains(res, expectedErr) {
+			continue
+		}
+		if res != c.expected {
+			t.Errorf("%s: %q, expected %q", c.arg, res, c.expected)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"text/template"
+
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/types"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/image"
+	"gopull/pkgs/retry"
+)
+
+// fileNameReplacer replaces the characters which can not appear in a file name, or would make it ambiguous
+// as a docker-archive: reference.
+var fileNameReplacer = strings.NewReplacer("/", "_", `\`, "_", ":", "_")
+
+// parseNameTemplate parses the --name-template value text.
+func parseNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("name-template").Parse(text)
+	if err != nil {
+		return nil, i18n.Errorf("invalid --name-template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// tarNameData is the data of --name-template. Slashes and colons in all values are replaced by "_".
+// The digest and the platform are read from the source image when the template uses them.
+type tarNameData struct {
+	Registry   string // e.g. docker.io
+	Repository string // e.g. library_redis
+	Name       string // e.g. redis
+	Tag        string // The tag in the image name, "latest" if it contains neither a tag nor a digest
+
+	ctx       context.Context
+	sys       *types.SystemContext
+	ref       types.ImageReference // The source image
+	retryOpts *retry.Options
+	digest    string // Of the image name, or of the manifest of ref once read
+	inspected *types.ImageInspectInfo
+}
+
+// newTarNameData returns the data of --name-template for parsedImage, the image name, read from srcRef using sys.
+func newTarNameData(ctx context.Context, parsedImage image.ImageStruct, srcRef types.ImageReference, sys *types.SystemContext, retryOpts *retry.Options) *tarNameData {
+	tag := parsedImage.Tag
+	if tag == "" && parsedImage.Digest == "" {
+		tag = "latest"
+	}
+	return &tarNameData{
+		Registry:   fileNameReplacer.Replace(parsedImage.Registry),
+		Repository: fileNameReplacer.Replace(parsedImage.Repository),
+		Name:       fileNameReplacer.Replace(parsedImage.Name),
+		Tag:        fileNameReplacer.Replace(tag),
+		ctx:        ctx,
+		sys:        sys,
+		ref:        srcRef,
+		retryOpts:  retryOpts,
+		digest:     parsedImage.Digest,
+	}
+}
+
+// inspect reads the manifest digest and the platform of the source image, if not done yet.
+func (d *tarNameData) inspect() (retErr error) {
+	if d.inspected != nil {
+		return nil
+	}
+	src, img, err := openImage(d.ctx, d.sys, d.ref, d.retryOpts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			retErr = noteCloseFailure(retErr, "closing image", err)
+		}
+	}()
+	if d.digest == "" {
+		// The digest of the manifest list, if any, as in image names; not of the instance chosen for the platform.
+		rawManifest, _, err := src.GetManifest(d.ctx, nil)
+		if err != nil {
+			return i18n.Errorf("Error retrieving manifest for image: %w", err)
+		}
+		digest, err := manifest.Digest(rawManifest)
+		if err != nil {
+			return i18n.Errorf("Error computing manifest digest: %w", err)
+		}
+		d.digest = digest.String()
+	}
+	inspected, err := img.Inspect(d.ctx)
+	if err != nil {
+		return err
+	}
+	d.inspected = inspected
+	return nil
+}
+
+// Digest returns the digest of the image, e.g. sha256_0123….
+func (d *tarNameData) Digest() (string, error) {
+	if d.digest == "" {
+		if err := d.inspect(); err != nil {
+			return "", err
+		}
+	}
+	return fileNameReplacer.Replace(d.digest), nil
+}
+
+// ShortDigest returns the first 12 hexadecimal digits of the digest of the image.
+func (d *tarNameData) ShortDigest() (string, error) {
+	if d.digest == "" {
+		if err := d.inspect(); err != nil {
+			return "", err
+		}
+	}
+	_, hex, _ := strings.Cut(d.digest, ":")
+	return hex[:min(12, len(hex))], nil
+}
+
+// Arch returns the architecture of the image, e.g. amd64.
+func (d *tarNameData) Arch() (string, error) {
+	if err := d.inspect(); err != nil {
+		return "", err
+	}
+	return fileNameReplacer.Replace(d.inspected.Architecture), nil
+}
+
+// OS returns the operating system of the image, e.g. linux.
+func (d *tarNameData) OS() (string, error) {
+	if err := d.inspect(); err != nil {
+		return "", err
+	}
+	return fileNameReplacer.Replace(d.inspected.Os), nil
+}
+
+// Variant returns the architecture variant of the image, e.g. v8, or "".
+func (d *tarNameData) Variant() (string, error) {
+	if err := d.inspect(); err != nil {
+		return "", err
+	}
+	return fileNameReplacer.Replace(d.inspected.Variant), nil
+}
+
+// Platform returns the platform of the image, e.g. linux_arm64_v8.
+func (d *tarNameData) Platform() (string, error) {
+	if err := d.inspect(); err != nil {
+		return "", err
+	}
+	platform := []string{d.inspected.Os, d.inspected.Architecture}
+	if d.inspected.Variant != "" {
+		platform = append(platform, d.inspected.Variant)
+	}
+	return fileNameReplacer.Replace(strings.Join(platform, "_")), nil
+}
+
+// executeNameTemplate returns the file name tmpl produces for data.
+func executeNameTemplate(tmpl *template.Template, data *tarNameData) (string, error) {
+	var name strings.Builder
+	if err := tmpl.Execute(&name, data); err != nil {
+		return "", i18n.Errorf("executing --name-template: %w", err)
+	}
+	res := name.String()
+	if res == "" || res == "." || res == ".." || strings.ContainsAny(res, `/\`) {
+		return "", i18n.Errorf("--name-template produced %q, which is not a file name", res)
+	}
+	return res, nil
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +11,7 @@ import (
 	dockerdistributionapi "github.com/docker/distribution/registry/api/v2"
 	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 
+	"gopull/pkgs/i18n"
 	"gopull/pkgs/image"
 
 	commonFlag "github.com/containers/common/pkg/flag"
@@ -19,7 +19,6 @@ import (
 	"github.com/containers/image/v5/storage"
 	"github.com/containers/image/v5/tarball"
 	"github.com/containers/image/v5/transports"
-	"github.com/containers/image/v5/transports/alltransports"
 	"github.com/containers/image/v5/types"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -50,7 +49,7 @@ func commandAction(handler func(args []string, stdout io.Writer) error) func(cmd
 // adjustUsage uses usageTemplate template to get rid the GlobalOption from usage
 // and disable [flag] at the end of command usage
 func adjustUsage(c *cobra.Command) {
-	c.SetUsageTemplate(usageTemplate)
+	c.SetUsageTemplate(i18n.T(usageTemplate))
 	c.DisableFlagsInUseLine = true
 }
 
@@ -78,7 +77,7 @@ func (opts *deprecatedTLSVerifyOption) warnIfUsed(alternatives []string) {
 func deprecatedTLSVerifyFlags() (pflag.FlagSet, *deprecatedTLSVerifyOption) {
 	opts := deprecatedTLSVerifyOption{}
 	fs := pflag.FlagSet{}
-	flag := commonFlag.OptionalBoolFlag(&fs, &opts.tlsVerify, "tls-verify", "require HTTPS and verify certificates when accessing the container registry")
+	flag := commonFlag.OptionalBoolFlag(&fs, &opts.tlsVerify, "tls-verify", i18n.T("require HTTPS and verify certificates when accessing the container registry"))
 	flag.Hidden = true
 	return fs, &opts
 }
@@ -91,27 +90,28 @@ func dockerImageFlags(global *globalOptions, shared *sharedImageOptions, depreca
 			global:              global,
 			shared:              shared,
 			deprecatedTLSVerify: deprecatedTLSVerify,
+			flagPrefix:          flagPrefix,
 		},
 	}
 
 	fs := pflag.FlagSet{}
 	if flagPrefix != "" {
 		// the non-prefixed flag is handled by a shared flag.
-		fs.Var(commonFlag.NewOptionalStringValue(&flags.authFilePath), flagPrefix+"authfile", "path of the authentication file. Default is ${XDG_RUNTIME_DIR}/containers/auth.json")
+		fs.Var(commonFlag.NewOptionalStringValue(&flags.authFilePath), flagPrefix+"authfile", i18n.T("path of the authentication file. Default is ${XDG_RUNTIME_DIR}/containers/auth.json"))
 	}
-	fs.Var(commonFlag.NewOptionalStringValue(&flags.credsOption), flagPrefix+"creds", "Use `USERNAME[:PASSWORD]` for accessing the registry")
-	fs.Var(commonFlag.NewOptionalStringValue(&flags.userName), flagPrefix+"username", "Username for accessing the registry")
-	fs.Var(commonFlag.NewOptionalStringValue(&flags.password), flagPrefix+"password", "Password for accessing the registry")
+	fs.Var(commonFlag.NewOptionalStringValue(&flags.credsOption), flagPrefix+"creds", i18n.T("Use `USERNAME[:PASSWORD]` for accessing the registry"))
+	fs.Var(commonFlag.NewOptionalStringValue(&flags.userName), flagPrefix+"username", i18n.T("Username for accessing the registry"))
+	fs.Var(commonFlag.NewOptionalStringValue(&flags.password), flagPrefix+"password", i18n.T("Password for accessing the registry"))
 	if credsOptionAlias != "" {
 		// This is horribly ugly, but we need to support the old option forms of (skopeo copy) for compatibility.
 		// Don't add any more cases like this.
 		f := fs.VarPF(commonFlag.NewOptionalStringValue(&flags.credsOption), credsOptionAlias, "", "Use `USERNAME[:PASSWORD]` for accessing the registry")
 		f.Hidden = true
 	}
-	fs.Var(commonFlag.NewOptionalStringValue(&flags.registryToken), flagPrefix+"registry-token", "Provide a Bearer token for accessing the registry")
-	fs.StringVar(&flags.dockerCertPath, flagPrefix+"cert-dir", "", "use certificates at `PATH` (*.crt, *.cert, *.key) to connect to the registry or daemon")
-	commonFlag.OptionalBoolFlag(&fs, &flags.tlsVerify, flagPrefix+"tls-verify", "require HTTPS and verify certificates when talking to the container registry or daemon")
-	fs.BoolVar(&flags.noCreds, flagPrefix+"no-creds", false, "Access the registry anonymously")
+	fs.Var(commonFlag.NewOptionalStringValue(&flags.registryToken), flagPrefix+"registry-token", i18n.T("Provide a Bearer token for accessing the registry"))
+	fs.StringVar(&flags.dockerCertPath, flagPrefix+"cert-dir", "", i18n.T("use certificates at `PATH` (*.crt, *.cert, *.key) to connect to the registry or daemon"))
+	commonFlag.OptionalBoolFlag(&fs, &flags.tlsVerify, flagPrefix+"tls-verify", i18n.T("require HTTPS and verify certificates when talking to the container registry or daemon"))
+	fs.BoolVar(&flags.noCreds, flagPrefix+"no-creds", false, i18n.T("Access the registry anonymously"))
 	return fs, &flags
 }
 
@@ -120,9 +120,10 @@ func imageFlags(global *globalOptions, shared *sharedImageOptions, deprecatedTLS
 	dockerFlags, opts := dockerImageFlags(global, shared, deprecatedTLSVerify, flagPrefix, credsOptionAlias)
 
 	fs := pflag.FlagSet{}
-	fs.StringVar(&opts.sharedBlobDir, flagPrefix+"shared-blob-dir", "", "`DIRECTORY` to use to share blobs across OCI repositories")
-	fs.StringVar(&opts.dockerDaemonHost, flagPrefix+"daemon-host", "", "use docker daemon host at `HOST` (docker-daemon: only)")
+	fs.StringVar(&opts.sharedBlobDir, flagPrefix+"shared-blob-dir", "", i18n.T("`DIRECTORY` to use to share blobs across OCI repositories"))
+	fs.StringVar(&opts.dockerDaemonHost, flagPrefix+"daemon-host", "", i18n.T("use docker daemon host at `HOST` (docker-daemon: only)"))
 	fs.AddFlagSet(&dockerFlags)
+	bindEnvironment(&fs)
 	return fs, opts
 }
 
@@ -132,12 +133,13 @@ func imageDestFlags(global *globalOptions, shared *sharedImageOptions, deprecate
 	opts := imageDestOptions{imageOptions: genericOptions, imageDestFlagPrefix: flagPrefix}
 	fs := pflag.FlagSet{}
 	fs.AddFlagSet(&genericFlags)
-	fs.BoolVar(&opts.dirForceCompression, flagPrefix+"compress", false, "Compress tarball image layers when saving to directory using the 'dir' transport. (default is same compression type as source)")
-	fs.BoolVar(&opts.dirForceDecompression, flagPrefix+"decompress", false, "Decompress tarball image layers when saving to directory using the 'dir' transport. (default is same compression type as source)")
-	fs.BoolVar(&opts.ociAcceptUncompressedLayers, flagPrefix+"oci-accept-uncompressed-layers", false, "Allow uncompressed image layers when saving to an OCI image using the 'oci' transport. (default is to compress things that aren't compressed)")
-	fs.StringVar(&opts.compressionFormat, flagPrefix+"compress-format", "", "`FORMAT` to use for the compression")
-	fs.Var(commonFlag.NewOptionalIntValue(&opts.compressionLevel), flagPrefix+"compress-level", "`LEVEL` to use for the compression")
-	fs.BoolVar(&opts.precomputeDigests, flagPrefix+"precompute-digests", false, "Precompute digests to prevent uploading layers already on the registry using the 'docker' transport.")
+	fs.BoolVar(&opts.dirForceCompression, flagPrefix+"compress", false, i18n.T("Compress tarball image layers when saving to directory using the 'dir' transport. (default is same compression type as source)"))
+	fs.BoolVar(&opts.dirForceDecompression, flagPrefix+"decompress", false, i18n.T("Decompress tarball image layers when saving to directory using the 'dir' transport. (default is same compression type as source)"))
+	fs.BoolVar(&opts.ociAcceptUncompressedLayers, flagPrefix+"oci-accept-uncompressed-layers", false, i18n.T("Allow uncompressed image layers when saving to an OCI image using the 'oci' transport. (default is to compress things that aren't compressed)"))
+	fs.StringVar(&opts.compressionFormat, flagPrefix+"compress-format", "", i18n.T("`FORMAT` to use for the compression"))
+	fs.Var(commonFlag.NewOptionalIntValue(&opts.compressionLevel), flagPrefix+"compress-level", i18n.T("`LEVEL` to use for the compression"))
+	fs.BoolVar(&opts.precomputeDigests, flagPrefix+"precompute-digests", false, i18n.T("Precompute digests to prevent uploading layers already on the registry using the 'docker' transport."))
+	bindEnvironment(&fs)
 	return fs, &opts
 }
 
@@ -170,11 +172,11 @@ func autocompleteSupportedTransports(cmd *cobra.Command, args []string, toComple
 
 func parseCreds(creds string) (string, string, error) {
 	if creds == "" {
-		return "", "", errors.New("credentials can't be empty")
+		return "", "", i18n.New("credentials can't be empty")
 	}
 	username, password, _ := strings.Cut(creds, ":") // Sets password to "" if there is no ":"
 	if username == "" {
-		return "", "", errors.New("username can't be empty")
+		return "", "", i18n.New("username can't be empty")
 	}
 	return username, password, nil
 }
@@ -201,7 +203,7 @@ func parseManifestFormat(manifestFormat string) (string, error) {
 	case "v2s2":
 		return manifest.DockerV2Schema2MediaType, nil
 	default:
-		return "", fmt.Errorf("unknown format %q. Choose one of the supported formats: 'oci', 'v2s1', or 'v2s2'", manifestFormat)
+		return "", i18n.Errorf("unknown format %q. Choose one of the supported formats: 'oci', 'v2s1', or 'v2s2'", manifestFormat)
 	}
 }
 
@@ -225,20 +227,6 @@ func isDockerManifestUnknownError(err error) bool {
 	return ec.ErrorCode() == dockerdistributionapi.ErrorCodeManifestUnknown
 }
 
-// parseImageSource converts image URL-like string to an ImageSource.
-// The caller must call .Close() on the returned ImageSource.
-func parseImageSource(ctx context.Context, opts *imageOptions, name string) (types.ImageSource, error) {
-	ref, err := alltransports.ParseImageName(name)
-	if err != nil {
-		return nil, err
-	}
-	sys, err := opts.newSystemContext()
-	if err != nil {
-		return nil, err
-	}
-	return ref.NewImageSource(ctx, sys)
-}
-
 func getDefaultImageTarName(data image.ImageStruct) string {
 	tarName := data.Name
 	if data.Tag != "" {
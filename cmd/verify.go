@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+	"github.com/containers/storage/pkg/homedir"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"gopull/pkgs/i18n"
+	"gopull/pkgs/retry"
+)
+
+// verifyOptions collects CLI flags which require the source image to be signed by specific keys,
+// using a policy built in memory instead of the trust policy files.
+type verifyOptions struct {
+	sigstoreKey string // Path to a sigstore (cosign) public key
+	gpgKey      string // Path to a GPG public keyring
+}
+
+// verifyFlags prepares a collection of CLI flags writing into verifyOptions, and the managed verifyOptions structure.
+func verifyFlags() (pflag.FlagSet, *verifyOptions) {
+	opts := verifyOptions{}
+	fs := pflag.FlagSet{}
+	fs.StringVar(&opts.sigstoreKey, "verify-key", "", i18n.T("require a sigstore signature by the public key at `PATH` (e.g. cosign.pub)"))
+	fs.StringVar(&opts.gpgKey, "verify-gpg", "", i18n.T("require a GPG signature by a key in the public keyring at `PATH`"))
+	return fs, &opts
+}
+
+// enabled returns true if any signature was required by the user.
+func (opts *verifyOptions) enabled() bool {
+	return opts.sigstoreKey != "" || opts.gpgKey != ""
+}
+
+// policyScope returns the scope of ref the in-memory policy applies to:
+// the whole repository for registry images, the image itself otherwise.
+func policyScope(ref types.ImageReference) string {
+	if ref.Transport() == docker.Transport {
+		if namespaces := ref.PolicyConfigurationNamespaces(); len(namespaces) != 0 {
+			return namespaces[0]
+		}
+	}
+	return ref.PolicyConfigurationIdentity()
+}
+
+// policy returns a policy accepting only ref's scope, and only with signatures by the configured keys.
+// If both a sigstore and a GPG key are configured, both signatures are required.
+func (opts *verifyOptions) policy(ref types.ImageReference) (*signature.Policy, error) {
+	var requirements signature.PolicyRequirements
+	if opts.sigstoreKey != "" {
+		pr, err := signature.NewPRSigstoreSignedKeyPath(opts.sigstoreKey, signature.NewPRMMatchRepoDigestOrExact())
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, pr)
+	}
+	if opts.gpgKey != "" {
+		pr, err := signature.NewPRSignedByKeyPath(signature.SBKeyTypeGPGKeys, opts.gpgKey, signature.NewPRMMatchRepoDigestOrExact())
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, pr)
+	}
+	return &signature.Policy{
+		Default: signature.PolicyRequirements{signature.NewPRReject()},
+		Transports: map[string]signature.PolicyTransportScopes{
+			ref.Transport().Name(): {policyScope(ref): requirements},
+		},
+	}, nil
+}
+
+// getPolicyContext returns a *signature.PolicyContext for copying or verifying ref:
+// the in-memory policy if any key is configured, the global policy otherwise.
+func (opts *verifyOptions) getPolicyContext(global *globalOptions, ref types.ImageReference) (*signature.PolicyContext, error) {
+	if !opts.enabled() {
+		return global.getPolicyContext()
+	}
+	if global.insecurePolicy || global.policyPath != "" {
+		return nil, i18n.New("--verify-key and --verify-gpg can not be used together with --policy or --insecure-policy")
+	}
+	for _, path := range []string{opts.sigstoreKey, opts.gpgKey} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, i18n.Errorf("reading verification key: %w", err)
+		}
+	}
+	policy, err := opts.policy(ref)
+	if err != nil {
+		return nil, err
+	}
+	return signature.NewPolicyContext(policy)
+}
+
+// enableSigstoreAttachments updates sys so that sigstore signatures of ref are read from the registry,
+// if a sigstore key is configured.
+// The returned cleanup function must be called when sys is no longer used.
+func (opts *verifyOptions) enableSigstoreAttachments(sys *types.SystemContext, ref types.ImageReference) (func(), error) {
+	if opts.sigstoreKey == "" {
+		return func() {}, nil
+	}
+	return enableSigstoreAttachments(sys, ref)
+}
+
+// enableSigstoreAttachments updates sys so that sigstore signatures of ref are read from and written to the registry,
+// which registries.d does not enable by default; the existing registries.d configuration is otherwise kept.
+// The returned cleanup function must be called when sys is no longer used.
+func enableSigstoreAttachments(sys *types.SystemContext, ref types.ImageReference) (func(), error) {
+	if ref.Transport() != docker.Transport {
+		return func() {}, nil
+	}
+	registriesDir := sys.RegistriesDirPath
+	if registriesDir == "" {
+		registriesDir = filepath.Join(homedir.Get(), ".config/containers/registries.d")
+		if _, err := os.Stat(registriesDir); err != nil {
+			registriesDir = "/etc/containers/registries.d"
+		}
+	}
+
+	dir, err := os.MkdirTemp(sys.BigFilesTemporaryDir, "gopull-registries.d")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	entries, err := os.ReadDir(registriesDir)
+	if err != nil && !os.IsNotExist(err) {
+		cleanup()
+		return nil, err
+	}
+	// A namespace can only be configured in one file, so an existing entry of ref is updated in place.
+	identity := ref.PolicyConfigurationIdentity()
+	merged := false
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+		path := filepath.Join(registriesDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err == nil && !merged {
+			var updated bool
+			data, updated, err = setUseSigstoreAttachments(data, identity)
+			if err != nil {
+				err = fmt.Errorf("parsing %s: %w", path, err)
+			}
+			merged = updated
+		}
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, entry.Name()), data, 0o600)
+		}
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+	if !merged {
+		config := fmt.Sprintf("docker:\n  %q:\n    use-sigstore-attachments: true\n", identity)
+		if err := os.WriteFile(filepath.Join(dir, "zz-gopull-verify.yaml"), []byte(config), 0o600); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+	sys.RegistriesDirPath = dir
+	return cleanup, nil
+}
+
+// setUseSigstoreAttachments returns data, a registries.d file, with use-sigstore-attachments set for the docker
+// namespace identity, and true, if data configures identity; otherwise it returns data unchanged and false.
+func setUseSigstoreAttachments(data []byte, identity string) ([]byte, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, false, err
+	}
+	if len(doc.Content) == 0 {
+		return data, false, nil
+	}
+	namespace := yamlMappingValue(yamlMappingValue(doc.Content[0], "docker"), identity)
+	if namespace == nil {
+		return data, false, nil
+	}
+	if namespace.Kind != yaml.MappingNode { // "identity:" without settings
+		*namespace = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	value := yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"}
+	if existing := yamlMappingValue(namespace, "use-sigstore-attachments"); existing != nil {
+		*existing = value
+	} else {
+		namespace.Content = append(namespace.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "use-sigstore-attachments"}, &value)
+	}
+	res, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, false, err
+	}
+	return res, true, nil
+}
+
+// yamlMappingValue returns the value of key in node, or nil if node is not a mapping containing key.
+func yamlMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// signatureReport describes the signatures of an image.
+type signatureReport struct {
+	Signatures      *int   `json:",omitempty"` // nil unless looked up, with --verify-key or --verify-gpg
+	SignatureStatus string // "unchecked" (no key to verify with), "verified" or "rejected"
+	SignatureError  string `json:",omitempty"` // Why the signatures were rejected
+}
+
+// checkSignatures counts the signatures of the image in src and verifies them, if any key is configured;
+// otherwise, the signatures are not read.
+// A rejected image is not an error, it is recorded in the returned report.
+// sigstore signatures are only found if src was opened using a SystemContext updated by enableSigstoreAttachments.
+func (opts *verifyOptions) checkSignatures(ctx context.Context, global *globalOptions, src types.ImageSource, retryOpts *retry.Options) (_ signatureReport, retErr error) {
+	if !opts.enabled() {
+		return signatureReport{SignatureStatus: "unchecked"}, nil
+	}
+	var signatures [][]byte
+	if err := retry.IfNecessary(ctx, func() error {
+		var err error
+		signatures, err = src.GetSignatures(ctx, nil)
+		return err
+	}, retryOpts); err != nil {
+		return signatureReport{}, i18n.Errorf("Error reading signatures: %w", err)
+	}
+	count := len(signatures)
+	res := signatureReport{Signatures: &count}
+
+	policyContext, err := opts.getPolicyContext(global, src.Reference())
+	if err != nil {
+		return signatureReport{}, i18n.Errorf("error loading trust policy: %v", err)
+	}
+	defer func() {
+		if err := policyContext.Destroy(); err != nil {
+			retErr = noteCloseFailure(retErr, "tearing down policy context", err)
+		}
+	}()
+	if allowed, err := policyContext.IsRunningImageAllowed(ctx, image.UnparsedInstance(src, nil)); allowed {
+		res.SignatureStatus = "verified"
+	} else {
+		res.SignatureStatus = "rejected"
+		res.SignatureError = err.Error()
+	}
+	return res, nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/types"
+	"github.com/distribution/reference"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetUseSigstoreAttachments(t *testing.T) {
+	const identity = "registry.example.com/app:1.0"
+	for _, c := range []struct {
+		data    string
+		updated bool
+		lookup  string // Expected lookaside of identity after the update, if updated
+	}{
+		{"docker:\n  registry.example.com/app:1.0:\n    lookaside: https://sigs.example.com\n", true, "https://sigs.example.com"},
+		{"docker:\n  registry.example.com/app:1.0:\n    use-sigstore-attachments: false\n", true, ""},
+		{"docker:\n  registry.example.com/app:1.0:\n", true, ""},
+		{"docker:\n  registry.example.com:\n    use-sigstore-attachments: false\n", false, ""},
+		{"default-docker:\n  use-sigstore-attachments: false\n", false, ""},
+		{"", false, ""},
+	} {
+		res, updated, err := setUseSigstoreAttachments([]byte(c.data), identity)
+		if err != nil {
+			t.Errorf("%q: %v", c.data, err)
+			continue
+		}
+		if updated != c.updated {
+			t.Errorf("%q: updated %v, expected %v", c.data, updated, c.updated)
+			continue
+		}
+		if !updated {
+			if string(res) != c.data {
+				t.Errorf("%q: changed to %q", c.data, res)
+			}
+			continue
+		}
+		var config struct {
+			Docker map[string]struct {
+				Lookaside              string `yaml:"lookaside"`
+				UseSigstoreAttachments *bool  `yaml:"use-sigstore-attachments"`
+			} `yaml:"docker"`
+		}
+		if err := yaml.Unmarshal(res, &config); err != nil {
+			t.Fatalf("%q: parsing the result %q: %v", c.data, res, err)
+		}
+		ns := config.Docker[identity]
+		if ns.UseSigstoreAttachments == nil || !*ns.UseSigstoreAttachments || ns.Lookaside != c.lookup {
+			t.Errorf("%q: result %q", c.data, res)
+		}
+	}
+}
+
+// TestEnableSigstoreAttachmentsExistingNamespace checks that the registries.d copy stays loadable by containers/image
+// when the namespace of the image is already configured.
+func TestEnableSigstoreAttachmentsExistingNamespace(t *testing.T) {
+	registriesDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(registriesDir, "app.yaml"),
+		[]byte("docker:\n  registry.example.com/app:1.0:\n    lookaside: https://sigs.example.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	named, err := reference.ParseNormalizedNamed("registry.example.com/app:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := docker.NewReference(named)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys := &types.SystemContext{RegistriesDirPath: registriesDir, BigFilesTemporaryDir: t.TempDir()}
+	cleanup, err := enableSigstoreAttachments(sys, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	entries, err := os.ReadDir(sys.RegistriesDirPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("registries.d copy contains %d files, expected only the updated app.yaml", len(entries))
+	}
+	// Loading the configuration fails if the namespace is defined twice.
+	if _, err := docker.SignatureStorageBaseURL(sys, ref, false); err != nil {
+		t.Errorf("loading the registries.d copy: %v", err)
+	}
+}
+
+func TestSignatureReportJSON(t *testing.T) {
+	none := 0
+	for _, c := range []struct {
+		report   signatureReport
+		expected string
+	}{
+		{signatureReport{SignatureStatus: "unchecked"}, `{"SignatureStatus":"unchecked"}`},
+		{signatureReport{Signatures: &none, SignatureStatus: "rejected", SignatureError: "no signature"}, `{"Signatures":0,"SignatureStatus":"rejected","SignatureError":"no signature"}`},
+	} {
+		res, err := json.Marshal(c.report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(res) != c.expected {
+			t.Errorf("%s, expected %s", res, c.expected)
+		}
+	}
+}
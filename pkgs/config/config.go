@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"gopull/pkgs/i18n"
+)
+
+// Config is the content of a gopull configuration file, e.g.
+//
+//	defaults:
+//	  authfile: /etc/gopull/auth.json
+//	  retry-times: 3
+//	registries:
+//	  harbor.internal:5000:
+//	    tls-verify: false
+//	    credentials: env:HARBOR_CREDS
+//	  docker.io:
+//	    mirror: mirror.internal/dockerhub
+//	aliases:
+//	  prod: harbor.prod.corp/platform
+//	profiles:
+//	  staging:
+//	    dest-tls-verify: false
+type Config struct {
+	// Defaults contains default values of command line options, indexed by the option name without "--".
+	// A value is a scalar, or a list for options which can be repeated.
+	Defaults map[string]any `yaml:"defaults"`
+	// Registries contains the settings of registries, indexed by host[:port] as in image names, e.g. "docker.io".
+	Registries map[string]Registry `yaml:"registries"`
+	// Aliases contains the registries or namespaces which image names like @NAME/redis refer to, indexed by NAME.
+	Aliases map[string]string `yaml:"aliases"`
+	// Profiles contains sets of options chosen together, indexed by profile name; each is like Defaults.
+	Profiles map[string]map[string]any `yaml:"profiles"`
+}
+
+// Registry contains the settings of a single registry.
+type Registry struct {
+	TLSVerify   *bool  `yaml:"tls-verify"`  // Require HTTPS and verify certificates
+	CertDir     string `yaml:"cert-dir"`    // Directory with *.crt, *.cert and *.key files for connecting to the registry
+	AuthFile    string `yaml:"authfile"`    // Path of the authentication file
+	Credentials string `yaml:"credentials"` // Where to read USERNAME[:PASSWORD] from: "env:NAME" or "file:PATH"
+	Mirror      string `yaml:"mirror"`      // Location to pull images from instead, host[:port][/namespace]
+	Proxy       string `yaml:"proxy"`       // URL of the HTTP(S) proxy of commands accessing the registry, for all their connections
+	LimitRate   string `yaml:"limit-rate"`  // Bandwidth limit of blob transfers, e.g. "5M"
+}
+
+// DefaultPath returns the path of the configuration file used if none is chosen, $XDG_CONFIG_HOME/gopull/config.yaml
+// or ~/.config/gopull/config.yaml.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gopull", "config.yaml"), nil
+}
+
+// Load reads the configuration file at path.
+// Unknown settings are an error, so that typos don't go unnoticed.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, i18n.Errorf("parsing %s: %w", path, err)
+	}
+	for name, value := range c.Defaults {
+		if _, err := FlagValues(value); err != nil {
+			return nil, i18n.Errorf("parsing %s: defaults.%s: %w", path, name, err)
+		}
+	}
+	for profile, options := range c.Profiles {
+		for name, value := range options {
+			if _, err := FlagValues(value); err != nil {
+				return nil, i18n.Errorf("parsing %s: profiles.%s.%s: %w", path, profile, name, err)
+			}
+		}
+	}
+	for name, target := range c.Aliases {
+		if name == "" || strings.ContainsAny(name, "/:@") || target == "" {
+			return nil, i18n.Errorf("parsing %s: aliases.%s: invalid alias %q of %q", path, name, name, target)
+		}
+	}
+	for name, r := range c.Registries {
+		if r.Credentials != "" && !strings.HasPrefix(r.Credentials, "env:") && !strings.HasPrefix(r.Credentials, "file:") {
+			return nil, i18n.Errorf("parsing %s: registries.%s.credentials: %q is neither env:NAME nor file:PATH", path, name, r.Credentials)
+		}
+	}
+	return &c, nil
+}
+
+// Registry returns the settings of registry, a host[:port] as returned by reference.Domain.
+func (c *Config) Registry(registry string) (Registry, bool) {
+	if c == nil {
+		return Registry{}, false
+	}
+	r, ok := c.Registries[registry]
+	return r, ok
+}
+
+// FlagValues converts value, a value in Defaults, to the values to set for a command line option:
+// one for a scalar, one per element for a list.
+func FlagValues(value any) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, e := range v {
+			s, err := scalar(e)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, s)
+		}
+		return res, nil
+	default:
+		s, err := scalar(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+}
+
+func scalar(value any) (string, error) {
+	switch v := value.(type) {
+	case string, bool, int, float64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", i18n.Errorf("%v is not a string, number, boolean or a list of them", value)
+	}
+}
+
+// ReadCredentials returns the USERNAME[:PASSWORD] referenced by r.Credentials, or "" if it is not set.
+func (r Registry) ReadCredentials() (string, error) {
+	kind, location, _ := strings.Cut(r.Credentials, ":")
+	switch kind {
+	case "":
+		return "", nil
+	case "env":
+		value, ok := os.LookupEnv(location)
+		if !ok {
+			return "", i18n.Errorf("credentials environment variable %s is not set", location)
+		}
+		return value, nil
+	case "file":
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return "", i18n.Errorf("reading credentials: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", i18n.Errorf("unknown credentials reference %q", r.Credentials)
+}
@@ -0,0 +1,79 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Supported languages.
+const (
+	English = "en"
+	Chinese = "zh"
+)
+
+// catalogs contains the translations of English messages, indexed by language.
+// English messages are used as they are, so there is no English catalog.
+var catalogs = map[string]map[string]string{
+	Chinese: zh,
+}
+
+var current = English
+
+// Parse returns the supported language selected by value, a language name like "zh" or a locale like "zh_CN.UTF-8".
+func Parse(value string) (string, error) {
+	lang, _, _ := strings.Cut(strings.ToLower(value), ".")
+	lang, _, _ = strings.Cut(lang, "_")
+	lang, _, _ = strings.Cut(lang, "-")
+	switch lang {
+	case English, "c", "posix":
+		return English, nil
+	case Chinese:
+		return Chinese, nil
+	}
+	return "", Errorf("unsupported language %q, expected %s or %s", value, English, Chinese)
+}
+
+// Detect returns the language selected by the LC_ALL, LC_MESSAGES or LANG environment variables, in that order
+// of precedence; English if none of them selects a supported language.
+func Detect() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if lang, err := Parse(value); err == nil {
+				return lang
+			}
+			return English
+		}
+	}
+	return English
+}
+
+// SetLanguage sets the language of messages returned by T, one of the supported languages.
+func SetLanguage(lang string) {
+	current = lang
+}
+
+// Language returns the language of messages returned by T.
+func Language() string {
+	return current
+}
+
+// T returns the translation of msg, an English message, to the current language;
+// msg itself if there is no translation.
+func T(msg string) string {
+	if translated, ok := catalogs[current][msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Errorf is fmt.Errorf, with format translated by T.
+func Errorf(format string, args ...any) error {
+	return fmt.Errorf(T(format), args...)
+}
+
+// New is errors.New, with msg translated by T.
+func New(msg string) error {
+	return errors.New(T(msg))
+}
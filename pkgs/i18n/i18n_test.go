@@ -0,0 +1,31 @@
+package i18n
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		value    string
+		expected string // "" if value is not supported
+	}{
+		{"en", English},
+		{"en_US.UTF-8", English},
+		{"en-GB", English},
+		{"C", English},
+		{"C.UTF-8", English},
+		{"POSIX", English},
+		{"zh", Chinese},
+		{"zh_CN.UTF-8", Chinese},
+		{"zh-TW", Chinese},
+		{"ZH_cn", Chinese},
+		{"fr_FR.UTF-8", ""},
+		{"", ""},
+	} {
+		lang, err := Parse(c.value)
+		switch {
+		case c.expected == "" && err == nil:
+			t.Errorf("%q: %q, expected an error", c.value, lang)
+		case c.expected != "" && (err != nil || lang != c.expected):
+			t.Errorf("%q: %q, %v, expected %q", c.value, lang, err, c.expected)
+		}
+	}
+}
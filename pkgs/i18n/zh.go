@@ -0,0 +1,333 @@
+package i18n
+
+// zh is the Chinese catalog.
+var zh = map[string]string{
+	"Print a file from image IMAGE-NAME": "打印镜像 IMAGE-NAME 中的文件",
+	"Print the file at PATH in the filesystem of \"IMAGE-NAME\" to standard output.\n\nOnly the layers down to the one containing PATH are downloaded.\n\n\"IMAGE-NAME\" may be a registry reference, a \"transport:details\" name, or a path\nto a docker-archive or oci-archive file or an OCI layout directory.\n\nSupported transports:\n%s\n": "将 \"IMAGE-NAME\" 文件系统中 PATH 处的文件打印到标准输出。\n\n只下载到包含 PATH 的那一层为止的各层。\n\n\"IMAGE-NAME\" 可以是仓库引用、\"transport:details\" 格式的名称,或者 docker-archive、\noci-archive 文件或 OCI layout 目录的路径。\n\n支持的传输方式:\n%s\n",
+	"Exactly two arguments expected":      "需要正好两个参数",
+	"image is required":                   "需要指定镜像",
+	"error loading trust policy: %v":      "加载信任策略出错: %v",
+	"writing progress: %w":                "输出进度失败: %w",
+	"Copy a file out of image IMAGE-NAME": "从镜像 IMAGE-NAME 中复制文件",
+	"Copy the file at the absolute PATH in the filesystem of \"IMAGE-NAME\" to LOCAL-PATH.\nIf LOCAL-PATH is an existing directory, the file is copied into it.\n\nOnly the layers down to the one containing PATH are downloaded.\n\n\"IMAGE-NAME\" may be a registry reference, a \"transport:details\" name, or a path\nto a docker-archive or oci-archive file or an OCI layout directory.\n\nSupported transports:\n%s\n": "将 \"IMAGE-NAME\" 文件系统中绝对路径 PATH 处的文件复制到 LOCAL-PATH。\n如果 LOCAL-PATH 是已存在的目录,文件会被复制到该目录中。\n\n只下载到包含 PATH 的那一层为止的各层。\n\n\"IMAGE-NAME\" 可以是仓库引用、\"transport:details\" 格式的名称,或者 docker-archive、\noci-archive 文件或 OCI layout 目录的路径。\n\n支持的传输方式:\n%s\n",
+	"%q is not in the IMAGE-NAME:PATH format, with an absolute PATH": "%q 不是 IMAGE-NAME:PATH 格式,或者 PATH 不是绝对路径",
+	"download an image": "下载镜像",
+	"Container \"IMAGE-NAME\" uses a \"transport\":\"details\" format.\n\nSupported transports:\n%s\n\nSee skopeo(1) section \"IMAGE NAMES\" for the expected format\n": "容器 \"IMAGE-NAME\" 使用 \"transport\":\"details\" 格式。\n\n支持的传输方式:\n%s\n\n镜像名称的格式参见 skopeo(1) 的 \"IMAGE NAMES\" 一节\n",
+	"Suppress output information when copying images":                                                                   "复制镜像时不输出信息",
+	"MANIFEST TYPE (oci, v2s1, or v2s2) to use in the destination (default is manifest type of source, with fallbacks)": "目标使用的清单类型 MANIFEST TYPE (oci、v2s1 或 v2s2)(默认与源的清单类型相同,必要时回退)",
+	"Read a passphrase for signing an image from `PATH`":                                                                "从 `PATH` 读取签名镜像所用的口令",
+	"set dest tag ": "设置目标 tag ",
+	"Report the blobs, tags and files the download would transfer and write, without writing anything": "报告下载将传输和写入的层、tag 和文件,但不写入任何内容",
+	"Progress output `FORMAT`, text or json (newline-delimited events on standard output)":             "进度输出格式 `FORMAT`,text 或 json(在标准输出上输出按行分隔的事件)",
+	"failed to parse image name %s: %v":               "解析镜像名称 %s 失败: %v",
+	"invalid destination name %s: %v":                 "无效的目标名称 %s: %v",
+	"failed to add destination tag %s: %w":            "添加目标 tag %s 失败: %w",
+	"error parsing tag %v":                            "解析 tag 出错 %v",
+	"dest must be a tagged reference":                 "目标必须是带 tag 的引用",
+	"Source image rejected: %w":                       "源镜像被拒绝: %w",
+	"Error retrieving manifest for image: %w":         "获取镜像清单出错: %w",
+	"Error computing manifest digest: %w":             "计算清单 digest 出错: %w",
+	"Export the merged root filesystem of IMAGE-NAME": "导出 IMAGE-NAME 合并后的根文件系统",
+	"Apply the layers of \"IMAGE-NAME\" in order and write the resulting root filesystem\nto a directory, or to a tar file if PATH ends with \".tar\" (\"-\" for standard output).\nNo container engine is required.\n\n\"IMAGE-NAME\" may be a registry reference, a \"transport:details\" name, or a path\nto a docker-archive or oci-archive file or an OCI layout directory.\n\nSupported transports:\n%s\n": "按顺序应用 \"IMAGE-NAME\" 的各层,并将得到的根文件系统写入目录;如果 PATH 以 \".tar\"\n结尾则写入 tar 文件(\"-\" 表示标准输出)。\n不需要容器引擎。\n\n\"IMAGE-NAME\" 可以是仓库引用、\"transport:details\" 格式的名称,或者 docker-archive、\noci-archive 文件或 OCI layout 目录的路径。\n\n支持的传输方式:\n%s\n",
+	"write the root filesystem to `PATH`, a directory or a .tar file": "将根文件系统写入 `PATH`,目录或 .tar 文件",
+	"Exactly one argument expected":                                   "需要正好一个参数",
+	"--to is required":                                                "必须指定 --to",
+	"destination directory %s is not empty":                           "目标目录 %s 不为空",
+	"Error applying layer %s: %w":                                     "应用层 %s 出错: %w",
+	"Show the build history of image IMAGE-NAME":                      "显示镜像 IMAGE-NAME 的构建历史",
+	"Show how \"IMAGE-NAME\" was built, from the history recorded in its configuration,\nwithout pulling the image.\n\n\"IMAGE-NAME\" may be a registry reference, a \"transport:details\" name, or a path\nto a docker-archive or oci-archive file or an OCI layout directory.\n\nSupported transports:\n%s\n": "根据 \"IMAGE-NAME\" 配置中记录的历史显示它是如何构建的,不需要拉取镜像。\n\n\"IMAGE-NAME\" 可以是仓库引用、\"transport:details\" 格式的名称,或者 docker-archive、\noci-archive 文件或 OCI layout 目录的路径。\n\n支持的传输方式:\n%s\n",
+	"Format the output to a Go template, or json":        "使用 Go 模板或 json 格式化输出",
+	"Do not truncate the created_by column":              "不截断 created_by 列",
+	"Output a best-effort reconstructed Dockerfile":      "输出尽力重建的 Dockerfile",
+	"--dockerfile does not support the format option":    "--dockerfile 不支持 format 选项",
+	"Error reading OCI-formatted configuration data: %w": "读取 OCI 格式的配置数据出错: %w",
+	"Inspect image IMAGE-NAME":                           "查看镜像 IMAGE-NAME",
+	"Return low-level information about \"IMAGE-NAME\" in a registry/transport\n\n\"IMAGE-NAME\" may be a registry reference, a \"transport:details\" name, or a path\nto a docker-archive or oci-archive file or an OCI layout directory.\n\nWith --verify-key or --verify-gpg, the signatures of the image are read and verified,\nthe output includes their number, and the command fails if the image is rejected.\n\nSupported transports:\n%s\n\nSee skopeo(1) section \"IMAGE NAMES\" for the expected format\n": "返回仓库/传输方式中 \"IMAGE-NAME\" 的底层信息\n\n\"IMAGE-NAME\" 可以是仓库引用、\"transport:details\" 格式的名称,或者 docker-archive、\noci-archive 文件或 OCI layout 目录的路径。\n\n使用 --verify-key 或 --verify-gpg 时会读取并验证镜像的签名,输出包含签名数量,\n镜像被拒绝时命令失败。\n\n支持的传输方式:\n%s\n\n镜像名称的格式参见 skopeo(1) 的 \"IMAGE NAMES\" 一节\n",
+	"output raw manifest or configuration":                                  "输出原始清单或配置",
+	"output configuration":                                                  "输出配置",
+	"Format the output to a Go template":                                    "使用 Go 模板格式化输出",
+	"Do not list the available tags from the repository in the output":      "输出中不列出仓库中可用的 tag",
+	"raw output does not support format option":                             "原始输出不支持 format 选项",
+	"Error parsing image name %q: %w":                                       "解析镜像名称 %q 出错: %w",
+	"Error writing manifest to standard output: %w":                         "将清单写入标准输出出错: %w",
+	"Error parsing manifest for image: %w":                                  "解析镜像清单出错: %w",
+	"Error reading configuration blob: %w":                                  "读取配置 blob 出错: %w",
+	"Error writing configuration blob to standard output: %w":               "将配置 blob 写入标准输出出错: %w",
+	"Error writing OCI-formatted configuration data to standard output: %w": "将 OCI 格式的配置数据写入标准输出出错: %w",
+	"Error determining repository tags: %w":                                 "获取仓库 tag 出错: %w",
+	"Signature verification failed: %w":                                     "签名验证失败: %w",
+	"layer %s is encrypted":                                                 "层 %s 已加密",
+	"Error reading layer %s: %w":                                            "读取层 %s 出错: %w",
+	"Error decompressing layer %s: %w":                                      "解压层 %s 出错: %w",
+	"%s: no such file in image":                                             "%s: 镜像中没有该文件",
+	"%s is a directory":                                                     "%s 是目录",
+	"%s is not a regular file":                                              "%s 不是普通文件",
+	"%s: too many levels of symbolic links":                                 "%s: 符号链接层级过多",
+	"Login to a container registry":                                         "登录容器仓库",
+	"Login to a container registry on a specified server.":                  "登录指定服务器上的容器仓库。",
+	"require HTTPS and verify certificates when accessing the registry":     "访问仓库时要求使用 HTTPS 并验证证书",
+	"Logout of a container registry":                                        "登出容器仓库",
+	"Logout of a container registry on a specified server.":                 "登出指定服务器上的容器仓库。",
+	"List files in image IMAGE-NAME":                                        "列出镜像 IMAGE-NAME 中的文件",
+	"List the contents of PATH (default \"/\") in the merged filesystem of \"IMAGE-NAME\",\nwith the layer which added each entry, and the layer which last changed it.\n\n\"IMAGE-NAME\" may be a registry reference, a \"transport:details\" name, or a path\nto a docker-archive or oci-archive file or an OCI layout directory.\n\nSupported transports:\n%s\n": "列出 \"IMAGE-NAME\" 合并后的文件系统中 PATH(默认 \"/\")的内容,\n以及添加每个条目的层和最后修改它的层。\n\n\"IMAGE-NAME\" 可以是仓库引用、\"transport:details\" 格式的名称,或者 docker-archive、\noci-archive 文件或 OCI layout 目录的路径。\n\n支持的传输方式:\n%s\n",
+	"List subdirectories recursively":                                                       "递归列出子目录",
+	"One or two arguments expected":                                                         "需要一个或两个参数",
+	"creds and no-creds cannot be specified at the same time":                               "不能同时指定 creds 和 no-creds",
+	"username and no-creds cannot be specified at the same time":                            "不能同时指定 username 和 no-creds",
+	"creds and username cannot be specified at the same time":                               "不能同时指定 creds 和 username",
+	"password must be specified when username is specified":                                 "指定 username 时必须指定 password",
+	"username must be specified when password is specified":                                 "指定 password 时必须指定 username",
+	"path of the authentication file. Default is ${XDG_RUNTIME_DIR}/containers/auth.json":   "认证文件的路径。默认为 ${XDG_RUNTIME_DIR}/containers/auth.json",
+	"the number of times to possibly retry":                                                 "最多重试的次数",
+	"delay before the first retry, doubled for every following retry, with a random jitter": "第一次重试前的等待时间,之后每次重试加倍,并带有随机抖动",
+	"longest delay between retries":                                                         "两次重试之间的最长等待时间",
+	"requirement `TYPE`: accept, reject, signedBy (GPG) or sigstoreSigned":                  "要求类型 `TYPE`: accept、reject、signedBy(GPG)或 sigstoreSigned",
+	"public key at `PATH` for signedBy (a GPG keyring) and sigstoreSigned":                  "signedBy(GPG 公钥环)和 sigstoreSigned 使用的位于 `PATH` 的公钥",
+	"--key is required for --type %s":                                                       "--type %s 需要指定 --key",
+	"--key can not be used with --type %s":                                                  "--type %s 不能使用 --key",
+	"--type is required":                                                                    "必须指定 --type",
+	"unknown requirement type %q, expected accept, reject, signedBy or sigstoreSigned":      "未知的要求类型 %q,应为 accept、reject、signedBy 或 sigstoreSigned",
+	"Create and manage trust policies":                                                      "创建和管理信任策略",
+	"Create and manage the trust policy deciding which images may be pulled.\n\nThe policy is read from --policy, or else from %s, or else from\n%s. If none exists, a built-in default accepting any image is used.\n\nThe init, add-registry and set-default commands modify the --policy file, or else the\nper-user policy file.": "创建和管理决定哪些镜像可以被拉取的信任策略。\n\n策略从 --policy 读取,否则从 %s 读取,否则从\n%s 读取。如果都不存在,则使用接受任何镜像的内置默认策略。\n\ninit、add-registry 和 set-default 命令修改 --policy 文件,否则修改\n当前用户的策略文件。",
+	"Create a policy file with the built-in default policy":      "使用内置默认策略创建策略文件",
+	"Overwrite an existing policy file":                          "覆盖已存在的策略文件",
+	"No arguments expected":                                      "不需要参数",
+	"policy file %s already exists, use --force to overwrite it": "策略文件 %s 已存在,使用 --force 覆盖",
+	"Show the effective policy, and the file it is read from":    "显示生效的策略及其来源文件",
+	"Set the requirement for images in SCOPE":                    "设置 SCOPE 中镜像的要求",
+	"Set the requirement for images in SCOPE, replacing any existing requirement for SCOPE.\n\nFor the docker transport, SCOPE is a registry (\"registry.example.com\"), a namespace\nor repository (\"registry.example.com/team/app\"), a single image (\"...app:1.0\"), or a\nwildcard of registries (\"*.example.com\"). The most specific scope matching an image applies.": "设置 SCOPE 中镜像的要求,替换 SCOPE 已有的要求。\n\n对于 docker 传输方式,SCOPE 可以是仓库服务器(\"registry.example.com\")、命名空间\n或仓库(\"registry.example.com/team/app\")、单个镜像(\"...app:1.0\"),或者\n仓库服务器通配符(\"*.example.com\")。镜像使用与之匹配的最具体的范围。",
+	"`TRANSPORT` the scope applies to":                      "范围适用的传输方式 `TRANSPORT`",
+	"unknown transport %q":                                  "未知的传输方式 %q",
+	"invalid scope %q: %w":                                  "无效的范围 %q: %w",
+	"Set the requirement for images not matching any scope": "设置不匹配任何范围的镜像的要求",
+	"Explain whether the policy accepts image IMAGE-NAME":   "说明策略是否接受镜像 IMAGE-NAME",
+	"Show which scope of the policy applies to \"IMAGE-NAME\" and its requirements, and whether\nthe image would be accepted. Signatures are only read if a requirement needs them.": "显示策略中适用于 \"IMAGE-NAME\" 的范围及其要求,以及镜像是否会被接受。\n只有要求需要签名时才会读取签名。",
+	"image rejected by the policy":                  "镜像被策略拒绝",
+	"unknown progress format %q, expected %s or %s": "未知的进度格式 %q,应为 %s 或 %s",
+	"pull an image to docker":                       "将镜像拉取到 docker",
+	"Report the blobs and tags the pull would transfer and write, without writing anything": "报告拉取将传输和写入的层和 tag,但不写入任何内容",
+	"set dest tag":               "设置目标 tag",
+	"invalid source name %s: %v": "无效的源名称 %s: %v",
+	"the source image name (redis@%s...) contains a digest, the destination tag must be set explicitly with -t or --tag": "源镜像名称(redis@%s...)包含 digest,需要显式设置目标 tag,请使用 -t 或者 --tag 参数来设置",
+	"push an image": "推送镜像",
+	"Container \"IMAGE-NAME\" uses a \"transport\":\"details\" format.\n\nWith --sign-by or --sign-by-sigstore-private-key the pushed image is signed. sigstore\nsignatures are stored in the registry as attachments, GPG signatures in the lookaside\nstorage configured in registries.d (see --registries.d).\n\nSupported transports:\n%s\n\nSee skopeo(1) section \"IMAGE NAMES\" for the expected format\n": "容器 \"IMAGE-NAME\" 使用 \"transport\":\"details\" 格式。\n\n使用 --sign-by 或 --sign-by-sigstore-private-key 时会对推送的镜像签名。sigstore\n签名作为附件存储在仓库中,GPG 签名存储在 registries.d 中配置的 lookaside\n存储中(参见 --registries.d)。\n\n支持的传输方式:\n%s\n\n镜像名称的格式参见 skopeo(1) 的 \"IMAGE NAMES\" 一节\n",
+	"Report the blobs and tags the push would transfer and write, without writing anything": "报告推送将传输和写入的层和 tag,但不写入任何内容",
+	"Push destination": "推送目标",
+	"Limit the transfer of blobs from and to registries to `RATE` bytes per second in total, e.g. 500k or 5M": "将与所有仓库之间的 blob 传输总共限制为每秒 `RATE` 字节,例如 500k 或 5M",
+	"Limit the transfer of blobs from and to a single registry, as `REGISTRY=RATE` (can be repeated)":         "限制与单个仓库之间的 blob 传输,格式为 `REGISTRY=RATE`(可重复)",
+	"--limit-rate: %w": "--limit-rate: %w",
+	"--registry-limit-rate: %q is not in the REGISTRY=RATE format":                                                 "--registry-limit-rate: %q 不是 REGISTRY=RATE 格式",
+	"--registry-limit-rate: %w":                                                                                    "--registry-limit-rate: %w",
+	"Unrecognized command `%[1]s %[2]s`\nTry '%[1]s --help' for more information":                                  "无法识别的命令 `%[1]s %[2]s`\n运行 '%[1]s --help' 查看更多信息",
+	"Unrecognized command `%[1]s %[2]s`\n\nDid you mean this?\n\t%[3]s\n\nTry '%[1]s --help' for more information": "无法识别的命令 `%[1]s %[2]s`\n\n您是不是要运行?\n\t%[3]s\n\n运行 '%[1]s --help' 查看更多信息",
+	"Missing command '%[1]s COMMAND'\nTry '%[1]s --help' for more information":                                     "缺少命令 '%[1]s COMMAND'\n运行 '%[1]s --help' 查看更多信息",
+	"Various operations with container images and container image registries":                                      "容器镜像和容器镜像仓库的各种操作",
+	"Version for Skopeo":                    "Skopeo 的版本",
+	"enable debug output":                   "启用调试输出",
+	"Path to a trust policy file":           "信任策略文件的路径",
+	"run the tool without any policy check": "不做任何策略检查运行",
+	"use registry configuration files in `DIR` (e.g. for container signature storage)":                           "使用 `DIR` 中的仓库配置文件(例如用于容器签名存储)",
+	"use `ARCH` instead of the architecture of the machine for choosing images":                                  "选择镜像时使用 `ARCH` 代替本机的架构",
+	"use `OS` instead of the running OS for choosing images":                                                     "选择镜像时使用 `OS` 代替当前运行的操作系统",
+	"use `VARIANT` instead of the running architecture variant for choosing images":                              "选择镜像时使用 `VARIANT` 代替当前运行的架构变体",
+	"timeout for the command execution":                                                                          "命令执行的超时时间",
+	"directory used to store temporary files":                                                                    "存放临时文件的目录",
+	"report a failure as `FORMAT`, text or json (an object with the category, exit code and message, on stderr)": "以 `FORMAT` 格式报告失败,text 或 json(在标准错误上输出包含类别、退出码和消息的对象)",
+	"language of messages, en or zh (default from LC_ALL, LC_MESSAGES or LANG)":                                  "消息的语言,en 或 zh(默认由 LC_ALL、LC_MESSAGES 或 LANG 决定)",
+	"Require HTTPS and verify certificates when accessing the registry":                                          "访问仓库时要求使用 HTTPS 并验证证书",
+	"unknown error format %q, expected %s or %s":                                                                 "未知的错误格式 %q,应为 %s 或 %s",
+	"Generate a software bill of materials for image IMAGE-NAME":                                                 "为镜像 IMAGE-NAME 生成软件物料清单",
+	"Generate a software bill of materials for \"IMAGE-NAME\", by reading the package\ndatabases and lock files in its layers; no vulnerability scanner or other network\nservice is used.\n\nRecognized sources:\n  dpkg (/var/lib/dpkg/status, status.d), apk (/lib/apk/db/installed),\n  rpm (rpmdb.sqlite), Go build information in executables,\n  npm (package-lock.json, yarn.lock), Python (Pipfile.lock, poetry.lock,\n  pinned requirements*.txt, installed *.dist-info/METADATA)\n\n\"IMAGE-NAME\" may be a registry reference, a \"transport:details\" name, or a path\nto a docker-archive or oci-archive file or an OCI layout directory.\n\nSupported transports:\n%s\n": "读取 \"IMAGE-NAME\" 各层中的包数据库和锁文件,为其生成软件物料清单;\n不使用漏洞扫描器或其他网络服务。\n\n识别的来源:\n  dpkg(/var/lib/dpkg/status、status.d)、apk(/lib/apk/db/installed)、\n  rpm(rpmdb.sqlite)、可执行文件中的 Go 构建信息、\n  npm(package-lock.json、yarn.lock)、Python(Pipfile.lock、poetry.lock、\n  固定版本的 requirements*.txt、已安装的 *.dist-info/METADATA)\n\n\"IMAGE-NAME\" 可以是仓库引用、\"transport:details\" 格式的名称,或者 docker-archive、\noci-archive 文件或 OCI layout 目录的路径。\n\n支持的传输方式:\n%s\n",
+	"Document `FORMAT`, %s or %s":                                                                                    "文档格式 `FORMAT`,%s 或 %s",
+	"Write the document to `FILE` instead of standard output":                                                        "将文档写入 `FILE` 而不是标准输出",
+	"Sign the image using a GPG key with the specified `FINGERPRINT`":                                                "使用指纹为 `FINGERPRINT` 的 GPG 密钥签名镜像",
+	"Sign the image using a sigstore private key at `PATH`":                                                          "使用位于 `PATH` 的 sigstore 私钥签名镜像",
+	"Identity of signed image, must be a fully specified docker reference. Defaults to the target docker reference.": "签名镜像的标识,必须是完整的 docker 引用。默认为目标 docker 引用。",
+	"Only one of --sign-by and --sign-by-sigstore-private-key can be used with --sign-passphrase-file":               "--sign-passphrase-file 只能与 --sign-by 和 --sign-by-sigstore-private-key 之一一起使用",
+	"Could not parse --sign-identity: %v":                                                                            "无法解析 --sign-identity: %v",
+	"Cannot prompt for a passphrase for key %s, not reading from a terminal":                                         "无法为密钥 %s 提示输入口令,不是从终端读取",
+	"Cannot prompt for a passphrase for key %s, stdin is not a terminal; use --sign-passphrase-file":                 "无法为密钥 %s 提示输入口令,标准输入不是终端;请使用 --sign-passphrase-file",
+	"Error reading password: %w":                                                                                     "读取密码出错: %w",
+	"%s is neither an OCI layout nor a dir: image":                                                                   "%s 既不是 OCI layout 也不是 dir: 镜像",
+	"reading %s: %w":                                          "读取 %s: %w",
+	"%s is not an image archive: %w":                          "%s 不是镜像归档: %w",
+	"%s is neither a docker-archive nor an oci-archive image": "%s 既不是 docker-archive 也不是 oci-archive 镜像",
+	"Error opening image %q: %w":                              "打开镜像 %q 出错: %w",
+	"require HTTPS and verify certificates when accessing the container registry":                                                                   "访问容器仓库时要求使用 HTTPS 并验证证书",
+	"Use `USERNAME[:PASSWORD]` for accessing the registry":                                                                                          "使用 `USERNAME[:PASSWORD]` 访问仓库",
+	"Username for accessing the registry":                                                                                                           "访问仓库的用户名",
+	"Password for accessing the registry":                                                                                                           "访问仓库的密码",
+	"Provide a Bearer token for accessing the registry":                                                                                             "提供访问仓库的 Bearer 令牌",
+	"use certificates at `PATH` (*.crt, *.cert, *.key) to connect to the registry or daemon":                                                        "使用 `PATH` 中的证书(*.crt、*.cert、*.key)连接仓库或守护进程",
+	"require HTTPS and verify certificates when talking to the container registry or daemon":                                                        "与容器仓库或守护进程通信时要求使用 HTTPS 并验证证书",
+	"Access the registry anonymously":                                                                                                               "匿名访问仓库",
+	"`DIRECTORY` to use to share blobs across OCI repositories":                                                                                     "用于在 OCI 仓库之间共享 blob 的目录 `DIRECTORY`",
+	"use docker daemon host at `HOST` (docker-daemon: only)":                                                                                        "使用位于 `HOST` 的 docker 守护进程(仅 docker-daemon:)",
+	"Compress tarball image layers when saving to directory using the 'dir' transport. (default is same compression type as source)":                "使用 'dir' 传输方式保存到目录时压缩 tarball 镜像层。(默认与源的压缩类型相同)",
+	"Decompress tarball image layers when saving to directory using the 'dir' transport. (default is same compression type as source)":              "使用 'dir' 传输方式保存到目录时解压 tarball 镜像层。(默认与源的压缩类型相同)",
+	"Allow uncompressed image layers when saving to an OCI image using the 'oci' transport. (default is to compress things that aren't compressed)": "使用 'oci' 传输方式保存为 OCI 镜像时允许未压缩的镜像层。(默认压缩未压缩的内容)",
+	"`FORMAT` to use for the compression":                                                                                                           "压缩使用的格式 `FORMAT`",
+	"`LEVEL` to use for the compression":                                                                                                            "压缩使用的级别 `LEVEL`",
+	"Precompute digests to prevent uploading layers already on the registry using the 'docker' transport.":                                          "使用 'docker' 传输方式时预先计算 digest,避免上传仓库中已有的层。",
+	"credentials can't be empty":                                                                                                                    "凭据不能为空",
+	"username can't be empty":                                                                                                                       "用户名不能为空",
+	"unknown format %q. Choose one of the supported formats: 'oci', 'v2s1', or 'v2s2'":                                                              "未知的格式 %q。请选择支持的格式之一: 'oci'、'v2s1' 或 'v2s2'",
+	"require a sigstore signature by the public key at `PATH` (e.g. cosign.pub)":                                                                    "要求有由位于 `PATH` 的公钥生成的 sigstore 签名(例如 cosign.pub)",
+	"require a GPG signature by a key in the public keyring at `PATH`":                                                                              "要求有由位于 `PATH` 的公钥环中的密钥生成的 GPG 签名",
+	"--verify-key and --verify-gpg can not be used together with --policy or --insecure-policy":                                                     "--verify-key 和 --verify-gpg 不能与 --policy 或 --insecure-policy 一起使用",
+	"reading verification key: %w":                                                                                                                  "读取验证密钥: %w",
+	"Error reading signatures: %w":                                                                                                                  "读取签名出错: %w",
+	"read default options and registry settings from the configuration file at `PATH` (default ~/.config/gopull/config.yaml)":                       "从位于 `PATH` 的配置文件读取默认选项和镜像仓库设置(默认为 ~/.config/gopull/config.yaml)",
+	"reading configuration file: %w":                                                                                                                "读取配置文件: %w",
+	"%s: invalid value %q for option %q: %w":                                                                                                        "%s: 选项 %[3]q 的值 %[2]q 无效: %[4]w",
+	"invalid mirror %q of %s: %w":                                                                                                                   "%[2]s 的镜像站 %[1]q 无效: %[3]w",
+	"limit-rate of registry %s in the configuration file: %w":                                                                                       "配置文件中镜像仓库 %s 的 limit-rate: %w",
+	"%s (env %s)": "%s(环境变量 %s)",
+	"options --%s and --%s are both set by environment variable %s":          "选项 --%s 和 --%s 都由环境变量 %s 设置",
+	"invalid value %q of environment variable %s: %w":                        "环境变量 %[2]s 的值 %[1]q 无效: %[3]w",
+	"%s: unknown option %q in %s":                                            "%s: %[3]s 中有未知的选项 %[2]q",
+	"%s: %s.%s: %w":                                                          "%s: %s.%s: %w",
+	"use the options of profile `NAME` in the configuration file":            "使用配置文件中名为 `NAME` 的 profile 的选项",
+	"profile %q is not defined, there is no configuration file %s":           "未定义 profile %q,配置文件 %s 不存在",
+	"profile %q is not defined in %s":                                        "%[2]s 中未定义 profile %[1]q",
+	"unknown short-name mode %q, expected enforcing, permissive or disabled": "未知的短名称模式 %q,应为 enforcing、permissive 或 disabled",
+	"resolving short name %q: %w":                                            "解析短名称 %q: %w",
+	"resolve ambiguous short names in `MODE`: enforcing (prompt, fail without a terminal), permissive (prompt, else try all registries) or disabled (try all registries); default from registries.conf": "以 `MODE` 模式解析有歧义的短名称: enforcing(提示选择,没有终端时失败)、permissive(提示选择,没有终端时依次尝试所有镜像仓库)或 disabled(依次尝试所有镜像仓库);默认由 registries.conf 决定",
+	"fail for short names which registries.conf does not resolve, instead of using Docker Hub":                                                                                                          "registries.conf 无法解析短名称时失败,而不是使用 Docker Hub",
+	"Write the archive to `PATH` (default NAME.TAG.tar)":                                                                                                                                                "将归档写入 `PATH`(默认为 NAME.TAG.tar)",
+	"Write the archive to `DIR`, created if needed, instead of the working directory":                                                                                                                   "将归档写入目录 `DIR`(不存在时创建),而不是工作目录",
+	"Go `TEMPLATE` of the archive file name, using .Registry, .Repository, .Name, .Tag, .Digest, .ShortDigest, .OS, .Arch, .Variant and .Platform":                                                      "归档文件名的 Go 模板 `TEMPLATE`,可使用 .Registry、.Repository、.Name、.Tag、.Digest、.ShortDigest、.OS、.Arch、.Variant 和 .Platform",
+	"--outfile and --name-template can not be used together":                                                                                                                                            "--outfile 和 --name-template 不能同时使用",
+	"creating output directory: %w":                                                      "创建输出目录失败: %w",
+	"invalid --name-template: %w":                                                        "无效的 --name-template: %w",
+	"executing --name-template: %w":                                                      "执行 --name-template 失败: %w",
+	"--name-template produced %q, which is not a file name":                              "--name-template 生成了 %q,不是文件名",
+	"Replace the archive if it already exists":                                           "归档已存在时替换它",
+	"Don't download the image if the archive already exists and contains the same image": "归档已存在且包含相同的镜像时不下载",
+	"%s already exists and contains a different image, use --force to replace it":        "%s 已存在且包含不同的镜像,使用 --force 替换它",
+	"%s already exists, use --force to replace it or --skip-existing to keep it":         "%s 已存在,使用 --force 替换它,或使用 --skip-existing 保留它",
+	"creating temporary file: %w":                                                        "创建临时文件失败: %w",
+	"renaming %s to %s: %w":                                                              "将 %s 重命名为 %s 失败: %w",
+	"Skipping %s, it already contains the image\n":                                       "跳过 %s,它已包含该镜像\n",
+	"interrupted": "已中断",
+	"%w: %s was not inspected, nothing was written":                                                                                       "%w: 未检查 %s,没有输出任何内容",
+	"Encrypt the layers for `PROTOCOL:KEY`, e.g. jwe:pub.pem, pkcs7:cert.pem or pgp:user@example.com (can be repeated)":                   "为 `PROTOCOL:KEY` 加密镜像层,例如 jwe:pub.pem、pkcs7:cert.pem 或 pgp:user@example.com(可重复)",
+	"Only encrypt the layers at `INDEXES`, 0 for the first layer, -1 for the last one (default all layers)":                               "只加密序号为 `INDEXES` 的层,0 为第一层,-1 为最后一层(默认加密所有层)",
+	"--encrypt-layer can only be used with --encryption-key":                                                                              "--encrypt-layer 只能与 --encryption-key 一起使用",
+	"invalid --encryption-key: %w":                                                                                                        "无效的 --encryption-key: %w",
+	"Decrypt the layers using the private key at `PATH[:PASSWORD]` (can be repeated)":                                                     "使用 `PATH[:PASSWORD]` 处的私钥解密镜像层(可重复)",
+	"invalid --decryption-key: %w":                                                                                                        "无效的 --decryption-key: %w",
+	"registries %s and %s have different proxies in the configuration file, a command can only use one proxy":                             "配置文件中镜像仓库 %s 和 %s 的代理不同, 一个命令只能使用一个代理",
+	"emptying temporary file: %w":                                                                                                         "清空临时文件失败: %w",
+	"--skip-existing can not be used with --encryption-key, an existing archive can't be checked to be encrypted for the same recipients": "--skip-existing 不能与 --encryption-key 同时使用, 无法检查已有归档文件是否为相同的接收者加密",
+	"%w: the copy of %s to %s was not planned, nothing was written":                                                                       "%w: 未完成 %s 到 %s 的复制计划, 没有写入任何内容",
+	"%w: %s was not copied to %s, the incomplete archive was removed":                                                                     "%w: %s 未复制到 %s, 不完整的归档文件已删除",
+	"%w: %s was not copied to %s, blobs already pushed remain in the registry":                                                            "%w: %s 未复制到 %s, 已上传的层仍保留在镜像仓库中",
+	"%w: %s was not copied to %s, the image is not tagged but layers the docker daemon already loaded remain":                             "%w: %s 未复制到 %s, 镜像未打 tag, 但 docker daemon 已导入的层仍然保留",
+	"%w: %s was not copied to %s":                                                                                                         "%w: %s 未复制到 %s",
+	"unsupported language %q, expected %s or %s":                                                                                          "不支持的语言 %q,应为 %s 或 %s",
+
+	// The usage template of commands.
+	"parsing %s: %w":                                 "解析 %s 失败: %w",
+	"parsing %s: defaults.%s: %w":                    "解析 %s 失败: defaults.%s: %w",
+	"parsing %s: profiles.%s.%s: %w":                 "解析 %s 失败: profiles.%s.%s: %w",
+	"parsing %s: aliases.%s: invalid alias %q of %q": "解析 %s 失败: aliases.%s: %q 不是 %q 的有效别名",
+	"parsing %s: registries.%s.credentials: %q is neither env:NAME nor file:PATH": "解析 %s 失败: registries.%s.credentials: %q 既不是 env:NAME 也不是 file:PATH",
+	"%v is not a string, number, boolean or a list of them":                       "%v 不是字符串、数字、布尔值或它们的列表",
+	"credentials environment variable %s is not set":                              "未设置凭据环境变量 %s",
+	"reading credentials: %w":                                                     "读取凭据失败: %w",
+	"unknown credentials reference %q":                                            "未知的凭据引用 %q",
+	"failed to parse image: \"%s\", err: %v":                                      "解析镜像 \"%s\" 失败: %v",
+	"invalid image \"%s\", expected @NAME/IMAGE":                                  "无效的镜像 \"%s\",应为 @NAME/IMAGE",
+	"unknown alias \"%s\" in image \"%s\"":                                        "未知的别名 \"%s\"(镜像 \"%s\")",
+	"invalid digest format in image string":                                       "镜像名称中的 digest 格式无效",
+	"invalid rate %q: %w":                                                         "无效的速率 %q: %w",
+	"invalid rate %q: must be positive":                                           "无效的速率 %q: 必须为正数",
+	"unknown SBOM format %q, expected %s or %s":                                   "未知的 SBOM 格式 %q,应为 %s 或 %s",
+	"Copying %s to %s\n":                                                          "正在复制 %s 到 %s\n",
+	"Copying %s %s skipped: already exists\n":                                     "复制 %s %s 已跳过: 已存在\n",
+	"Copying %s %s done (%s)\n":                                                   "复制 %s %s 完成 (%s)\n",
+	"Wrote manifest %s\n":                                                         "已写入清单 %s\n",
+	"Copied %s in %s (%s, %s)\n":                                                  "已复制 %s,用时 %s (%s, %s)\n",
+	"Copied %s in %s (%s, %s; %s skipped)\n":                                      "已复制 %s,用时 %s (%s, %s; 跳过 %s)\n",
+	"Progress: %s\n":                                                              "进度: %s\n",
+	" (limit %s/s)":                                                               " (限速 %s/s)",
+	"%3.0f%% %s / %s, %s, ETA %s, %d/%d blobs":                                    "%3.0f%% %s / %s, %s, 预计剩余 %s, %d/%d 个 blob",
+	"LAYER\tSIZE\tUNCOMPRESSED (EST.)":                                            "层\t大小\t解压后大小(估计)",
+	"Total download size: %s\n":                                                   "下载总大小: %s\n",
+	"Estimated size on disk: %s\n":                                                "估计占用磁盘: %s\n",
+	"Source: %s\n":                                                                "源: %s\n",
+	"Manifest: %s (%s)\n":                                                         "清单: %s (%s)\n",
+	"Destination: %s\n":                                                           "目标: %s\n",
+	"Tag: %s\n":                                                                   "tag: %s\n",
+	"Output file: %s (already exists, the copy would fail)\n":                     "输出文件: %s (已存在,复制会失败)\n",
+	"Output file: %s (the copy would fail: %v)\n":                                 "输出文件: %s (复制会失败: %v)\n",
+	"Output file: %s (already contains the image, the copy would be skipped)\n": "输出文件: %s (已包含该镜像,将跳过复制)\n",
+	"Output file: %s (already exists, it would be replaced)\n":                  "输出文件: %s (已存在,将被替换)\n",
+	"Output file: %s\n":                      "输出文件: %s\n",
+	"BLOB\tTYPE\tSIZE\tSTATUS":               "BLOB\t类型\t大小\t状态",
+	"transfer":                               "传输",
+	"exists":                                 "已存在",
+	"config":                                 "配置",
+	"layer":                                  "层",
+	"To transfer: %d blobs, %s\n":            "需要传输: %d 个 blob, %s\n",
+	"Already at destination: %d blobs, %s\n": "目标已存在: %d 个 blob, %s\n",
+	"CREATED\tCREATED BY\tSIZE\tCOMMENT":     "创建时间\t创建命令\t大小\t注释",
+	"%s ago":                                 "%s前",
+	"# Reconstructed from the image history; the build context and base image are not recoverable.": "# 根据镜像历史重建; 构建上下文和基础镜像无法恢复。",
+	"MODE\tUID:GID\tSIZE\tADDED\tCHANGED\tPATH":                                                     "权限\tUID:GID\t大小\t添加于\t修改于\t路径",
+	" link to /%s":                              " 硬链接到 /%s",
+	"Exported %d layers to %s\n":                "已将 %d 层导出到 %s\n",
+	"Copied %s (%s) to %s\n":                    "已将 %s (%s) 复制到 %s\n",
+	"Wrote %d packages to %s\n":                 "已将 %d 个软件包写入 %s\n",
+	"Passphrase for key %s: ":                   "密钥 %s 的口令: ",
+	"Wrote %s\n":                                "已写入 %s\n",
+	"Set %s scope %q to %s in %s\n":             "已将 %s 的范围 %q 设置为 %s,写入 %s\n",
+	"Set the default requirement to %s in %s\n": "已将默认要求设置为 %s,写入 %s\n",
+	"transport %q, scope %q (exact match)":      "传输方式 %q, 范围 %q (精确匹配)",
+	"transport %q, scope %q":                    "传输方式 %q, 范围 %q",
+	"transport %q, default scope":               "传输方式 %q, 默认范围",
+	"default":                                   "默认",
+	"(built-in default)":                        "(内置默认策略)",
+	"Image: %s\n":                               "镜像: %s\n",
+	"Policy: %s\n":                              "策略: %s\n",
+	"Section: %s\n":                             "所在部分: %s\n",
+	"Requirements:\n":                           "要求:\n",
+	"Result: rejected: %v\n":                    "结果: 拒绝: %v\n",
+	"Result: accepted\n":                        "结果: 接受\n",
+	`Usage:{{if .Runnable}}
+{{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
+
+{{.CommandPath}} [command]{{end}}{{if gt (len .Aliases) 0}}
+
+Aliases:
+{{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+Examples:
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}
+
+Available Commands:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+{{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+Flags:
+{{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+{{end}}
+`: `用法:{{if .Runnable}}
+{{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
+
+{{.CommandPath}} [命令]{{end}}{{if gt (len .Aliases) 0}}
+
+别名:
+{{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+示例:
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}
+
+可用命令:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+{{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+选项:
+{{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+{{end}}
+`,
+}
@@ -1,10 +1,11 @@
 package image
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/distribution/reference"
+
+	"gopull/pkgs/i18n"
 )
 
 // ImageStruct 表示解析后的镜像信息，符合 Kubernetes 和 Docker 社区规范
@@ -27,7 +28,7 @@ func ParseImageStr(image string) (ImageStruct, error) {
 
 	parsed, err := reference.ParseNormalizedNamed(image)
 	if err != nil {
-		return imageInfo, fmt.Errorf(`failed to parse image: "%s", err: %v`, image, err)
+		return imageInfo, i18n.Errorf(`failed to parse image: "%s", err: %v`, image, err)
 	}
 
 	imageInfo.Registry = reference.Domain(parsed)
@@ -39,12 +40,30 @@ func ParseImageStr(image string) (ImageStruct, error) {
 	return imageInfo, nil
 }
 
+// ExpandAlias 展开 @NAME/... 形式的镜像名中的别名 NAME，aliases 为别名到镜像仓库或命名空间的映射，
+// 例如 aliases 为 {"prod": "harbor.prod.corp/platform"} 时，@prod/redis:7 展开为 harbor.prod.corp/platform/redis:7。
+// 不以 @ 开头的镜像名原样返回；展开后的镜像名再由 ParseImageStr 解析和规范化
+func ExpandAlias(image string, aliases map[string]string) (string, error) {
+	if !strings.HasPrefix(image, "@") {
+		return image, nil
+	}
+	name, rest, ok := strings.Cut(image[1:], "/")
+	if !ok || rest == "" {
+		return "", i18n.Errorf(`invalid image "%s", expected @NAME/IMAGE`, image)
+	}
+	prefix, ok := aliases[name]
+	if !ok {
+		return "", i18n.Errorf(`unknown alias "%s" in image "%s"`, name, image)
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + rest, nil
+}
+
 // splitDigest 拆分出 @sha256:<digest> 并返回镜像和 digest
 func splitDigest(image string) (string, string, error) {
 	if strings.Contains(image, "@sha256:") {
 		parts := strings.Split(image, "@sha256:")
 		if len(parts) != 2 {
-			return "", "", fmt.Errorf("invalid digest format in image string")
+			return "", "", i18n.New("invalid digest format in image string")
 		}
 		return parts[0], "sha256:" + parts[1], nil
 	}
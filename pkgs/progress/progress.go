@@ -0,0 +1,93 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/containers/image/v5/types"
+)
+
+// EventType identifies what an Event reports.
+type EventType string
+
+// Event types, in the order they happen during a copy.
+const (
+	ImageStarted    EventType = "image-started"
+	BlobStarted     EventType = "blob-started"
+	BlobProgress    EventType = "blob-progress" // Bytes of a blob were transferred
+	BlobDone        EventType = "blob-done"
+	BlobSkipped     EventType = "blob-skipped" // The blob already exists at the destination and was reused
+	ManifestWritten EventType = "manifest-written"
+	ImageDone       EventType = "image-done"
+	Error           EventType = "error"
+)
+
+// Event is a single progress report of copying an image.
+type Event struct {
+	Type        EventType `json:"type"`
+	Time        time.Time `json:"time"`
+	Source      string    `json:"source,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	Digest      string    `json:"digest,omitempty"`      // Blob digest, or manifest digest for ManifestWritten and ImageDone
+	MediaType   string    `json:"mediaType,omitempty"`   // Blob or manifest media type
+	Size        int64     `json:"size,omitempty"`        // Blob size, -1 if unknown
+	Transferred uint64    `json:"transferred,omitempty"` // Bytes of the blob transferred so far
+	Delta       uint64    `json:"delta,omitempty"`       // Bytes transferred since the previous event of this blob
+	RateLimit   int64     `json:"rateLimit,omitempty"`   // Bandwidth limit of the copy in bytes per second, for ImageStarted
+	Blobs       []Blob    `json:"blobs,omitempty"`       // Blobs of the source image, for ImageStarted, if known before the copy
+	Error       string    `json:"error,omitempty"`
+}
+
+// Blob is a blob of an image to copy.
+type Blob struct {
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType,omitempty"`
+	Size      int64  `json:"size"` // -1 if unknown
+}
+
+// Forward converts the reports sent to ch by copy.Image into blob events sent to events, until ch is closed.
+// ch is drained continuously, so that the copy is never blocked by a slow consumer of events for longer than it takes to
+// hand an event over.
+func Forward(ch <-chan types.ProgressProperties, events chan<- Event) {
+	for props := range ch {
+		ev := Event{
+			Digest:    props.Artifact.Digest.String(),
+			MediaType: props.Artifact.MediaType,
+			Size:      props.Artifact.Size,
+		}
+		switch props.Event {
+		case types.ProgressEventNewArtifact:
+			ev.Type = BlobStarted
+		case types.ProgressEventRead:
+			ev.Type = BlobProgress
+			ev.Transferred = props.Offset
+			ev.Delta = props.OffsetUpdate
+		case types.ProgressEventDone:
+			ev.Type = BlobDone
+			ev.Transferred = props.Offset
+			ev.Delta = props.OffsetUpdate
+		case types.ProgressEventSkipped:
+			ev.Type = BlobSkipped
+		default:
+			continue
+		}
+		ev.Time = time.Now()
+		events <- ev
+	}
+}
+
+// WriteJSON writes each event received from events to w as a single line of JSON, until events is closed.
+// events is drained even after a write fails, so that senders are never blocked; the first error is returned.
+func WriteJSON(w io.Writer, events <-chan Event) error {
+	var res error
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for ev := range events {
+		if res != nil {
+			continue
+		}
+		res = enc.Encode(ev)
+	}
+	return res
+}
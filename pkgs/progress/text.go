@@ -0,0 +1,179 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"gopull/pkgs/i18n"
+)
+
+const (
+	// redrawInterval limits how often the status line is redrawn on a terminal.
+	redrawInterval = 200 * time.Millisecond
+	// logInterval is how often the totals are logged when not writing to a terminal.
+	logInterval = 5 * time.Second
+	barWidth    = 25
+)
+
+// textWriter writes a human-readable report of events.
+type textWriter struct {
+	w          io.Writer
+	tty        bool
+	totals     Totals
+	started    time.Time // Of the current image
+	copied     int64     // Totals.Copied when the current image started
+	skipped    int64     // Totals.Skipped when the current image started
+	rateLimit  int64     // Of the current image, 0 if not limited
+	statusLine bool      // A status line is displayed and must be cleared before writing other lines
+	lastStatus time.Time
+	err        error // The first write error; nothing is written after it
+}
+
+// WriteText writes a human-readable report of events to w, until events is closed.
+// Completed blobs and images are reported as separate lines.
+// If tty, a status line with the totals, throughput and ETA is redrawn in place below them;
+// otherwise, the totals are written as a separate line every few seconds.
+// events is drained even after a write fails, so that senders are never blocked; the first error is returned.
+func WriteText(w io.Writer, tty bool, events <-chan Event) error {
+	tw := &textWriter{w: w, tty: tty}
+	for ev := range events {
+		if tw.err == nil {
+			tw.handle(ev)
+		}
+	}
+	if tw.err == nil {
+		tw.clearStatus()
+	}
+	return tw.err
+}
+
+func (tw *textWriter) handle(ev Event) {
+	tw.totals.Update(ev)
+	switch ev.Type {
+	case ImageStarted:
+		tw.started = ev.Time
+		tw.copied, tw.skipped = tw.totals.Copied(), tw.totals.Skipped
+		tw.rateLimit = ev.RateLimit
+		if !tw.tty {
+			tw.lastStatus = ev.Time // The first totals are logged after logInterval
+		}
+		tw.printf(i18n.T("Copying %s to %s\n"), ev.Source, ev.Destination)
+	case BlobSkipped:
+		tw.printf(i18n.T("Copying %s %s skipped: already exists\n"), blobKind(ev.MediaType), shortDigest(ev.Digest))
+	case BlobDone:
+		tw.printf(i18n.T("Copying %s %s done (%s)\n"), blobKind(ev.MediaType), shortDigest(ev.Digest), HumanSize(ev.Size))
+	case ManifestWritten:
+		tw.printf(i18n.T("Wrote manifest %s\n"), ev.Digest)
+	case ImageDone:
+		// Only the bytes of this image actually copied count for the rate, not those of skipped blobs.
+		elapsed := ev.Time.Sub(tw.started)
+		copied := max(tw.totals.Copied()-tw.copied, 0)
+		if skipped := tw.totals.Skipped - tw.skipped; skipped > 0 {
+			tw.printf(i18n.T("Copied %s in %s (%s, %s; %s skipped)\n"), ev.Destination, elapsed.Round(time.Millisecond),
+				HumanSize(copied), averageRate(copied, elapsed), HumanSize(skipped))
+		} else {
+			tw.printf(i18n.T("Copied %s in %s (%s, %s)\n"), ev.Destination, elapsed.Round(time.Millisecond),
+				HumanSize(copied), averageRate(copied, elapsed))
+		}
+		return
+	case Error:
+		tw.clearStatus()
+		return
+	}
+	tw.status(ev.Time)
+}
+
+// printf writes a line above the status line.
+func (tw *textWriter) printf(format string, args ...any) {
+	tw.clearStatus()
+	if tw.err == nil {
+		_, tw.err = fmt.Fprintf(tw.w, format, args...)
+	}
+}
+
+// clearStatus removes the status line, if it is displayed.
+func (tw *textWriter) clearStatus() {
+	if !tw.statusLine || tw.err != nil {
+		return
+	}
+	_, tw.err = io.WriteString(tw.w, "\r\x1b[K")
+	tw.statusLine = false
+}
+
+// status reports the totals, at most every redrawInterval on a terminal or every logInterval otherwise.
+func (tw *textWriter) status(now time.Time) {
+	if tw.err != nil || tw.totals.Blobs == 0 {
+		return
+	}
+	if !tw.tty {
+		if now.Sub(tw.lastStatus) >= logInterval && tw.totals.DoneBlobs < tw.totals.Blobs {
+			_, tw.err = fmt.Fprintf(tw.w, i18n.T("Progress: %s\n"), tw.summary())
+			tw.lastStatus = now
+		}
+		return
+	}
+	if tw.statusLine && now.Sub(tw.lastStatus) < redrawInterval {
+		return
+	}
+	filled := int(tw.totals.Percent() / 100 * barWidth)
+	filled = max(0, min(filled, barWidth))
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	_, tw.err = fmt.Fprintf(tw.w, "\r\x1b[K[%s] %s", bar, tw.summary())
+	tw.statusLine = true
+	tw.lastStatus = now
+}
+
+// summary returns the totals, throughput and ETA on a single line.
+func (tw *textWriter) summary() string {
+	t := &tw.totals
+	eta := "--"
+	if d, ok := t.ETA(); ok {
+		eta = d.Round(time.Second).String()
+	}
+	rate := HumanSize(int64(t.Rate())) + "/s"
+	if tw.rateLimit > 0 {
+		rate += fmt.Sprintf(i18n.T(" (limit %s/s)"), HumanSize(tw.rateLimit))
+	}
+	return fmt.Sprintf(i18n.T("%3.0f%% %s / %s, %s, ETA %s, %d/%d blobs"), t.Percent(),
+		HumanSize(t.Transferred), HumanSize(t.Size), rate, eta, t.DoneBlobs, t.Blobs)
+}
+
+// averageRate returns the throughput of transferring size bytes in elapsed.
+func averageRate(size int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "-- /s"
+	}
+	return HumanSize(int64(float64(size)/elapsed.Seconds())) + "/s"
+}
+
+// blobKind returns "config" or "blob", as copy.Image describes blobs of mediaType.
+func blobKind(mediaType string) string {
+	if strings.Contains(mediaType, "config") || mediaType == "application/vnd.docker.container.image.v1+json" {
+		return "config"
+	}
+	return "blob"
+}
+
+// shortDigest returns the first 12 hex characters of a digest.
+func shortDigest(digest string) string {
+	_, hex, ok := strings.Cut(digest, ":")
+	if !ok {
+		hex = digest
+	}
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	return hex
+}
+
+// HumanSize formats size with human-readable units, or "unknown" for negative values.
+func HumanSize(size int64) string {
+	if size < 0 {
+		return "unknown"
+	}
+	return units.HumanSizeWithPrecision(float64(size), 3)
+}
@@ -0,0 +1,32 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTextImageDone(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	events := make(chan Event, 10)
+	events <- Event{Type: ImageStarted, Time: at(0), Source: "docker://alpine", Destination: "dir:alpine", Blobs: []Blob{
+		{Digest: "sha256:config", Size: 1000},
+		{Digest: "sha256:layer", Size: 3000000},
+	}}
+	events <- Event{Type: BlobSkipped, Time: at(0), Digest: "sha256:layer", Size: 3000000}
+	events <- Event{Type: BlobStarted, Time: at(0), Digest: "sha256:config", Size: 1000}
+	events <- Event{Type: BlobDone, Time: at(2), Digest: "sha256:config", Transferred: 1000}
+	events <- Event{Type: ImageDone, Time: at(2), Destination: "dir:alpine"}
+	close(events)
+	var buf bytes.Buffer
+	if err := WriteText(&buf, false, events); err != nil {
+		t.Fatal(err)
+	}
+	// Only the config was copied; the skipped layer is reported separately.
+	expected := "Copied dir:alpine in 2s (1kB, 500B/s; 3MB skipped)\n"
+	if !strings.HasSuffix(buf.String(), expected) {
+		t.Errorf("wrote %q, expected it to end with %q", buf.String(), expected)
+	}
+}
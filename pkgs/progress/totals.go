@@ -0,0 +1,163 @@
+package progress
+
+import (
+	"time"
+)
+
+// rateWindow is the period over which Totals.Rate averages the throughput.
+const rateWindow = 5 * time.Second
+
+// Totals aggregates the progress of all blobs reported by a sequence of events, across images.
+//
+// Sizes are those of the blobs as stored at the source. The totals include the blobs listed by ImageStarted events
+// before their copy starts; blobs which are not listed are added when their copy starts, because copy.Image reports
+// blobs as it starts copying them. Layers may be decompressed while copying, so that more bytes are written than the blob size;
+// the bytes of a blob are counted only up to its size.
+type Totals struct {
+	Blobs        int   // Number of blobs listed, started or skipped
+	DoneBlobs    int   // Number of blobs copied or skipped
+	SkippedBlobs int   // Number of blobs which already existed at the destination
+	Size         int64 // Sum of the known sizes of the blobs
+	Transferred  int64 // Bytes of Size copied, including skipped blobs
+	Skipped      int64 // Bytes of Transferred from skipped blobs, which were not actually copied
+
+	blobs   map[string]*blobTotals // Indexed by digest
+	samples []sample               // Bytes actually copied over the last rateWindow, oldest first
+}
+
+type blobTotals struct {
+	size    int64 // -1 if unknown
+	counted int64 // Bytes included in Totals.Transferred
+	done    bool
+	skipped bool
+}
+
+type sample struct {
+	time        time.Time
+	transferred int64
+}
+
+// Update adds ev to the totals.
+func (t *Totals) Update(ev Event) {
+	if t.blobs == nil {
+		t.blobs = map[string]*blobTotals{}
+	}
+	switch ev.Type {
+	case ImageStarted:
+		for _, blob := range ev.Blobs {
+			if _, ok := t.blobs[blob.Digest]; !ok {
+				t.add(blob.Digest, blob.Size)
+			}
+		}
+		return
+	case BlobStarted, BlobSkipped:
+		size := ev.Size
+		if b, ok := t.blobs[ev.Digest]; ok {
+			// Listed by ImageStarted, or copied again, by a retry or as part of another image.
+			t.Transferred -= b.counted
+			if b.done {
+				t.DoneBlobs--
+			}
+			if b.skipped {
+				t.Skipped -= b.counted
+				t.SkippedBlobs--
+			}
+			t.Size -= max(b.size, 0)
+			t.Blobs--
+			if size < 0 {
+				size = b.size
+			}
+		}
+		b := t.add(ev.Digest, size)
+		if ev.Type == BlobSkipped {
+			t.SkippedBlobs++
+			b.skipped = true
+			t.finish(b)
+		}
+	case BlobProgress:
+		if b, ok := t.blobs[ev.Digest]; ok && !b.done {
+			t.count(b, int64(ev.Transferred))
+		}
+	case BlobDone:
+		if b, ok := t.blobs[ev.Digest]; ok && !b.done {
+			t.finish(b)
+		}
+	default:
+		return
+	}
+	t.addSample(ev.Time)
+}
+
+// add records the blob digest of size, -1 if unknown, and returns its totals.
+func (t *Totals) add(digest string, size int64) *blobTotals {
+	b := &blobTotals{size: size}
+	t.blobs[digest] = b
+	t.Blobs++
+	t.Size += max(size, 0)
+	return b
+}
+
+// count sets the bytes of b transferred so far.
+func (t *Totals) count(b *blobTotals, transferred int64) {
+	if b.size >= 0 {
+		transferred = min(transferred, b.size)
+	}
+	t.Transferred += transferred - b.counted
+	if b.skipped {
+		t.Skipped += transferred - b.counted
+	}
+	b.counted = transferred
+}
+
+// finish records that b is complete.
+func (t *Totals) finish(b *blobTotals) {
+	if b.size >= 0 {
+		t.count(b, b.size)
+	}
+	b.done = true
+	t.DoneBlobs++
+}
+
+func (t *Totals) addSample(now time.Time) {
+	t.samples = append(t.samples, sample{time: now, transferred: t.Copied()})
+	i := 0
+	for i < len(t.samples)-2 && now.Sub(t.samples[i+1].time) >= rateWindow {
+		i++
+	}
+	t.samples = t.samples[i:]
+}
+
+// Copied returns the bytes of Transferred actually copied, i.e. not from skipped blobs.
+func (t *Totals) Copied() int64 {
+	return t.Transferred - t.Skipped
+}
+
+// Rate returns the throughput in bytes per second, averaged over the last few seconds; skipped blobs are not included.
+func (t *Totals) Rate() float64 {
+	if len(t.samples) < 2 {
+		return 0
+	}
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	elapsed := last.time.Sub(first.time).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.transferred-first.transferred) / elapsed
+}
+
+// Percent returns the percentage of Size transferred.
+func (t *Totals) Percent() float64 {
+	if t.Size <= 0 {
+		return 0
+	}
+	return 100 * float64(t.Transferred) / float64(t.Size)
+}
+
+// ETA returns the estimated time until all blobs of Size are copied, or false if it can't be estimated yet.
+func (t *Totals) ETA() (time.Duration, bool) {
+	rate := t.Rate()
+	if rate <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(t.Size-t.Transferred) / rate * float64(time.Second)), true
+}
@@ -0,0 +1,53 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTotalsSeededByImageStarted(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	var totals Totals
+	totals.Update(Event{Type: ImageStarted, Time: at(0), Blobs: []Blob{
+		{Digest: "sha256:config", Size: 1000},
+		{Digest: "sha256:layer1", Size: 10000},
+		{Digest: "sha256:layer2", Size: 89000},
+	}})
+	if totals.Blobs != 3 || totals.Size != 100000 || totals.Percent() != 0 {
+		t.Fatalf("before the copy: %d blobs, size %d, %.0f%%", totals.Blobs, totals.Size, totals.Percent())
+	}
+
+	totals.Update(Event{Type: BlobStarted, Time: at(0), Digest: "sha256:layer1", Size: 10000})
+	totals.Update(Event{Type: BlobProgress, Time: at(1), Digest: "sha256:layer1", Transferred: 5000})
+	totals.Update(Event{Type: BlobDone, Time: at(2), Digest: "sha256:layer1", Transferred: 10000})
+	if totals.Blobs != 3 || totals.DoneBlobs != 1 || totals.Size != 100000 || totals.Percent() != 10 {
+		t.Errorf("after the first layer: %d/%d blobs, size %d, %.0f%%", totals.DoneBlobs, totals.Blobs, totals.Size, totals.Percent())
+	}
+	// 10000 bytes in 2s, 90000 bytes left.
+	if eta, ok := totals.ETA(); !ok || eta != 18*time.Second {
+		t.Errorf("ETA %s, %v, expected 18s", eta, ok)
+	}
+
+	// A blob whose size copy.Image doesn't report keeps the listed size.
+	totals.Update(Event{Type: BlobStarted, Time: at(2), Digest: "sha256:layer2", Size: -1})
+	totals.Update(Event{Type: BlobDone, Time: at(3), Digest: "sha256:layer2", Transferred: 95000})
+	// A blob which was not listed is added.
+	totals.Update(Event{Type: BlobSkipped, Time: at(3), Digest: "sha256:other", Size: 500})
+	totals.Update(Event{Type: BlobStarted, Time: at(3), Digest: "sha256:config", Size: 1000})
+	totals.Update(Event{Type: BlobDone, Time: at(3), Digest: "sha256:config", Transferred: 1000})
+	if totals.Blobs != 4 || totals.DoneBlobs != 4 || totals.SkippedBlobs != 1 || totals.Size != 100500 || totals.Transferred != 100500 ||
+		totals.Skipped != 500 || totals.Copied() != 100000 {
+		t.Errorf("after the copy: %+v", totals)
+	}
+	// The skipped blob does not count for the throughput: 100000 bytes copied in 3s.
+	if rate := totals.Rate(); rate != 100000/3.0 {
+		t.Errorf("rate %.0f, expected %.0f", rate, 100000/3.0)
+	}
+
+	// The same image again, e.g. after a failed attempt, doesn't count its blobs twice.
+	totals.Update(Event{Type: ImageStarted, Time: at(4), Blobs: []Blob{{Digest: "sha256:config", Size: 1000}}})
+	if totals.Blobs != 4 || totals.Size != 100500 {
+		t.Errorf("after starting again: %d blobs, size %d", totals.Blobs, totals.Size)
+	}
+}
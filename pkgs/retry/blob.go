@@ -0,0 +1,189 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+	"github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+)
+
+// reference is an ImageReference whose image sources retry reading blobs.
+type reference struct {
+	types.ImageReference
+	policyContext *signature.PolicyContext
+	policyLock    sync.Mutex // A PolicyContext can only check one image at a time
+	options       *Options
+}
+
+// WrapReference returns an ImageReference to ref, whose image sources retry every blob separately, as set in options:
+// opening a blob is retried, and a blob which fails while being read is opened again to continue
+// where it failed, so that a failure does not restart the whole copy.
+// Blob writes to image destinations can not be retried, because the data written is consumed.
+//
+// The sources only implement the public containers/image interfaces, which can't return sigstore signatures;
+// so every manifest they return is first accepted by policyContext, with the signatures of ref's own source.
+// The image of the returned reference must then be copied with a policy accepting anything, since its sources
+// return no sigstore signatures to check again.
+func WrapReference(ref types.ImageReference, policyContext *signature.PolicyContext, options *Options) types.ImageReference {
+	return &reference{ImageReference: ref, policyContext: policyContext, options: options}
+}
+
+func (ref *reference) NewImage(ctx context.Context, sys *types.SystemContext) (types.ImageCloser, error) {
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	img, err := image.FromSource(ctx, sys, src)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	return img, nil
+}
+
+func (ref *reference) NewImageSource(ctx context.Context, sys *types.SystemContext) (types.ImageSource, error) {
+	src, err := ref.ImageReference.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	return &imageSource{ImageSource: src, ref: ref, manifests: map[digest.Digest]acceptedManifest{}}, nil
+}
+
+type imageSource struct {
+	types.ImageSource
+	ref       *reference
+	manifests map[digest.Digest]acceptedManifest // Indexed by instance digest, "" for the primary manifest
+}
+
+// acceptedManifest is a manifest accepted by the policy.
+type acceptedManifest struct {
+	manifest []byte
+	mimeType string
+}
+
+func (src *imageSource) Reference() types.ImageReference {
+	return src.ref
+}
+
+// GetManifest returns the manifest of the instance, after checking that the policy accepts it.
+// The manifest returned is the one the policy checked, so that an image changed in the registry in the meantime
+// can't be copied unchecked.
+func (src *imageSource) GetManifest(ctx context.Context, instanceDigest *digest.Digest) ([]byte, string, error) {
+	var key digest.Digest
+	if instanceDigest != nil {
+		key = *instanceDigest
+	}
+	src.ref.policyLock.Lock()
+	defer src.ref.policyLock.Unlock()
+	if m, ok := src.manifests[key]; ok {
+		return m.manifest, m.mimeType, nil
+	}
+	unparsed := image.UnparsedInstance(src.ImageSource, instanceDigest)
+	if allowed, err := src.ref.policyContext.IsRunningImageAllowed(ctx, unparsed); !allowed {
+		return nil, "", err
+	}
+	manifest, mimeType, err := unparsed.Manifest(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	src.manifests[key] = acceptedManifest{manifest: manifest, mimeType: mimeType}
+	return manifest, mimeType, nil
+}
+
+func (src *imageSource) GetBlob(ctx context.Context, info types.BlobInfo, cache types.BlobInfoCache) (io.ReadCloser, int64, error) {
+	r := &blobReader{ctx: ctx, src: src.ImageSource, info: info, cache: cache, options: src.ref.options}
+	var size int64
+	if err := IfNecessary(ctx, func() error {
+		var err error
+		r.rc, size, err = src.ImageSource.GetBlob(ctx, info, cache)
+		return err
+	}, r.options); err != nil {
+		return nil, 0, err
+	}
+	return r, size, nil
+}
+
+// blobReader reads a blob, opening it again after a retryable failure.
+type blobReader struct {
+	ctx     context.Context
+	src     types.ImageSource
+	info    types.BlobInfo
+	cache   types.BlobInfoCache
+	options *Options
+
+	rc       io.ReadCloser
+	offset   int64 // Bytes returned by Read so far
+	attempts int   // Retries so far, of this blob
+	pending  error // A retryable failure of rc, reported together with data, to be handled by the next Read
+}
+
+func (r *blobReader) Read(p []byte) (int, error) {
+	for {
+		n, err := 0, r.pending
+		r.pending = nil
+		if err == nil {
+			n, err = r.rc.Read(p)
+			r.offset += int64(n)
+		}
+		if err == nil || err == io.EOF || !r.options.isRetryable(err) || r.attempts >= r.options.MaxRetry {
+			return n, err
+		}
+		if n > 0 {
+			r.pending = err
+			return n, nil
+		}
+		r.attempts++
+		delay := r.options.backoff(r.attempts - 1)
+		logrus.Warnf("Reading blob %s failed at %d bytes, retrying in %s ... (%d/%d). Error: %v", r.info.Digest, r.offset,
+			delay.Round(time.Millisecond), r.attempts, r.options.MaxRetry, err)
+		if !sleep(r.ctx, delay) {
+			return 0, err
+		}
+		if err := r.reopen(); err != nil {
+			// r.rc now fails with err, which is handled like any other failure.
+			logrus.Debugf("Reopening blob %s failed: %v", r.info.Digest, err)
+		}
+	}
+}
+
+// reopen replaces r.rc by a new reader of the blob, positioned at r.offset.
+func (r *blobReader) reopen() error {
+	r.rc.Close()
+	rc, _, err := r.src.GetBlob(r.ctx, r.info, r.cache)
+	if err != nil {
+		r.rc = errReader{err}
+		return err
+	}
+	if skipped, err := io.CopyN(io.Discard, rc, r.offset); err != nil {
+		rc.Close()
+		err = fmt.Errorf("skipping %d bytes of blob %s already read: skipped %d: %w", r.offset, r.info.Digest, skipped, err)
+		r.rc = errReader{err}
+		return err
+	}
+	r.rc = rc
+	return nil
+}
+
+func (r *blobReader) Close() error {
+	return r.rc.Close()
+}
+
+// errReader is an io.ReadCloser failing with err.
+type errReader struct {
+	err error
+}
+
+func (e errReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
+
+func (e errReader) Close() error {
+	return nil
+}
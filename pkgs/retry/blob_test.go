@@ -0,0 +1,137 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+	"github.com/opencontainers/go-digest"
+)
+
+// testReference is a registry reference whose sources are a testSource.
+type testReference struct {
+	types.ImageReference
+	src *testSource
+}
+
+func (ref testReference) NewImageSource(context.Context, *types.SystemContext) (types.ImageSource, error) {
+	return ref.src, nil
+}
+
+// testSource serves a single blob and a manifest; reading the blob fails with failures, in turn, after half of it.
+type testSource struct {
+	types.ImageSource
+	ref       types.ImageReference
+	blob      []byte
+	failures  []error
+	opened    int
+	manifests int
+}
+
+func (src *testSource) Reference() types.ImageReference {
+	return src.ref
+}
+
+func (src *testSource) GetManifest(context.Context, *digest.Digest) ([]byte, string, error) {
+	src.manifests++
+	return []byte("{}"), manifest.DockerV2Schema2MediaType, nil
+}
+
+func (src *testSource) GetBlob(context.Context, types.BlobInfo, types.BlobInfoCache) (io.ReadCloser, int64, error) {
+	src.opened++
+	if len(src.failures) == 0 {
+		return io.NopCloser(bytes.NewReader(src.blob)), int64(len(src.blob)), nil
+	}
+	err := src.failures[0]
+	src.failures = src.failures[1:]
+	return io.NopCloser(io.MultiReader(bytes.NewReader(src.blob[:len(src.blob)/2]), errReader{err})), int64(len(src.blob)), nil
+}
+
+func newTestReference(t *testing.T, failures ...error) (types.ImageReference, *testSource) {
+	t.Helper()
+	ref, err := docker.ParseReference("//registry.example.com/app:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &testSource{ref: ref, blob: []byte("0123456789abcdef"), failures: failures}
+	return testReference{ImageReference: ref, src: src}, src
+}
+
+func newTestPolicyContext(t *testing.T, requirement signature.PolicyRequirement) *signature.PolicyContext {
+	t.Helper()
+	policyContext, err := signature.NewPolicyContext(&signature.Policy{Default: signature.PolicyRequirements{requirement}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = policyContext.Destroy() })
+	return policyContext
+}
+
+func TestBlobReader(t *testing.T) {
+	options := &Options{MaxRetry: 2, Delay: time.Millisecond}
+	policyContext := newTestPolicyContext(t, signature.NewPRInsecureAcceptAnything())
+	for _, c := range []struct {
+		name     string
+		failures []error
+		opened   int
+		ok       bool
+	}{
+		{"no failure", nil, 1, true},
+		{"retryable failures", []error{syscall.ECONNRESET, io.ErrUnexpectedEOF}, 3, true},
+		{"too many failures", []error{syscall.ECONNRESET, syscall.ECONNRESET, syscall.ECONNRESET}, 3, false},
+		{"final failure", []error{errors.New("invalid")}, 1, false},
+	} {
+		ref, inner := newTestReference(t, c.failures...)
+		src, err := WrapReference(ref, policyContext, options).NewImageSource(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc, _, err := src.GetBlob(context.Background(), types.BlobInfo{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		if ok := err == nil; ok != c.ok || inner.opened != c.opened {
+			t.Errorf("%s: %v, opened %d times, expected success %v, opened %d times", c.name, err, inner.opened, c.ok, c.opened)
+		}
+		if c.ok && !bytes.Equal(data, inner.blob) {
+			t.Errorf("%s: read %q, expected %q", c.name, data, inner.blob)
+		}
+	}
+}
+
+func TestImageSourceGetManifest(t *testing.T) {
+	options := &Options{MaxRetry: 2, Delay: time.Millisecond}
+
+	ref, inner := newTestReference(t)
+	src, err := WrapReference(ref, newTestPolicyContext(t, signature.NewPRInsecureAcceptAnything()), options).NewImageSource(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, _, err := src.GetManifest(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.manifests != 1 {
+		t.Errorf("accepted manifest read %d times, expected once", inner.manifests)
+	}
+
+	ref, _ = newTestReference(t)
+	src, err = WrapReference(ref, newTestPolicyContext(t, signature.NewPRReject()), options).NewImageSource(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var policyErr signature.PolicyRequirementError
+	if _, _, err := src.GetManifest(context.Background(), nil); !errors.As(err, &policyErr) {
+		t.Errorf("rejected manifest: %v, expected a policy rejection", err)
+	}
+}
@@ -0,0 +1,182 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/signature"
+	"github.com/docker/distribution/registry/api/errcode"
+	errcodev2 "github.com/docker/distribution/registry/api/v2"
+	"github.com/hashicorp/go-multierror"
+	"github.com/sirupsen/logrus"
+)
+
+// Defaults used when Options.Delay or Options.MaxDelay are not set.
+const (
+	DefaultDelay    = time.Second
+	DefaultMaxDelay = 30 * time.Second
+)
+
+// Options defines how to retry.
+// It is compatible with the Options of github.com/containers/common/pkg/retry, except that Delay is the initial delay
+// of an exponential backoff instead of a fixed delay.
+type Options struct {
+	MaxRetry         int           // The number of times to possibly retry.
+	Delay            time.Duration // The delay before the first retry, doubled for every following retry; DefaultDelay if not set.
+	MaxDelay         time.Duration // The longest delay between retries; DefaultMaxDelay if not set.
+	IsErrorRetryable func(error) bool
+}
+
+// IfNecessary runs operation, and retries it with an exponential backoff while it fails with a retryable error,
+// up to options.MaxRetry times.
+func IfNecessary(ctx context.Context, operation func() error, options *Options) error {
+	err := operation()
+	for attempt := 0; err != nil && options.isRetryable(err) && attempt < options.MaxRetry; attempt++ {
+		delay := options.backoff(attempt)
+		logrus.Warnf("Failed, retrying in %s ... (%d/%d). Error: %v", delay.Round(time.Millisecond), attempt+1, options.MaxRetry, err)
+		if !sleep(ctx, delay) {
+			return err
+		}
+		err = operation()
+	}
+	return err
+}
+
+func (options *Options) isRetryable(err error) bool {
+	if options.IsErrorRetryable != nil {
+		return options.IsErrorRetryable(err)
+	}
+	return IsErrorRetryable(err)
+}
+
+// backoff returns the delay before retry attempt+1. It grows exponentially, with a random jitter so that many clients
+// failing at the same time don't all retry at the same time, and is never longer than MaxDelay.
+// containers/image already waits as long as a Retry-After header asks when it gets a 429 Too Many Requests response,
+// before failing. Retry-After is not honoured for 503 Service Unavailable responses: containers/image neither waits
+// for them nor returns the headers of any response in its errors, and offers no way to replace its HTTP client,
+// so the delay is not known here.
+func (options *Options) backoff(attempt int) time.Duration {
+	initial, maxDelay := options.Delay, options.MaxDelay
+	if initial <= 0 {
+		initial = DefaultDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxDelay
+	}
+	delay := maxDelay
+	if attempt < 32 && initial<<attempt > 0 {
+		delay = min(initial<<attempt, maxDelay)
+	}
+	// Equal jitter: at least half of the delay, so that the backoff still grows.
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// sleep waits for delay, and returns false if ctx is done first.
+func sleep(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// statusCodeRE matches the HTTP status in errors containers/image returns for unexpected registry responses.
+var statusCodeRE = regexp.MustCompile(`(?:invalid status code from registry|StatusCode:|received unexpected HTTP status:) (\d{3})\b`)
+
+// IsErrorRetryable makes a heuristic determination whether it is worth retrying upon encountering an error.
+// Failures which would happen again, like authentication failures, unknown images or manifests, policy or signature
+// rejections and invalid references, are not retried. Network failures and timeouts, 5xx responses
+// and 429 Too Many Requests are.
+func IsErrorRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var unauthorized docker.ErrUnauthorizedForCredentials
+	var policyErr signature.PolicyRequirementError
+	var invalidSig signature.InvalidSignatureError
+	if errors.As(err, &unauthorized) || errors.As(err, &policyErr) || errors.As(err, &invalidSig) {
+		return false
+	}
+	// A group of errors is only worth retrying if all of them are; check it before errors.Is, which would
+	// find a retryable error in a group also including final ones.
+	var group *multierror.Error
+	if errors.As(err, &group) {
+		for i := range group.Errors {
+			if !IsErrorRetryable(group.Errors[i]) {
+				return false
+			}
+		}
+		return len(group.Errors) != 0
+	}
+	if isTooManyRequests(err) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	switch e := err.(type) {
+	case errcode.Error:
+		switch e.Code {
+		case errcode.ErrorCodeUnauthorized, errcode.ErrorCodeDenied,
+			errcodev2.ErrorCodeNameUnknown, errcodev2.ErrorCodeManifestUnknown, errcodev2.ErrorCodeBlobUnknown,
+			errcodev2.ErrorCodeNameInvalid, errcodev2.ErrorCodeTagInvalid, errcodev2.ErrorCodeDigestInvalid,
+			errcodev2.ErrorCodeManifestInvalid, errcode.ErrorCodeUnsupported:
+			return false
+		}
+		return true
+	case errcode.Errors:
+		// if this error is a group of errors, process them all in turn
+		for i := range e {
+			if !IsErrorRetryable(e[i]) {
+				return false
+			}
+		}
+		return len(e) != 0
+	case *net.OpError:
+		return IsErrorRetryable(e.Err)
+	case *url.Error: // This includes errors returned by the net/http client.
+		if e.Err == io.EOF { // Happens when a server accepts a HTTP connection and sends EOF
+			return true
+		}
+		return IsErrorRetryable(e.Err)
+	case syscall.Errno:
+		return isErrnoRetryable(e)
+	case net.Error:
+		if e.Timeout() {
+			return true
+		}
+	}
+	if m := statusCodeRE.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return code >= 500 || code == 408
+	}
+	if next := errors.Unwrap(err); next != nil {
+		return IsErrorRetryable(next)
+	}
+	return false
+}
+
+// isTooManyRequests returns true if err reports a 429 Too Many Requests response.
+func isTooManyRequests(err error) bool {
+	var ec errcode.Error
+	return errors.Is(err, docker.ErrTooManyRequests) || (errors.As(err, &ec) && ec.Code == errcode.ErrorCodeTooManyRequests)
+}
+
+func isErrnoRetryable(e syscall.Errno) bool {
+	switch e {
+	case syscall.ECONNREFUSED, syscall.EINTR, syscall.EAGAIN, syscall.EBUSY, syscall.ENETDOWN, syscall.ENETUNREACH, syscall.ENETRESET,
+		syscall.ECONNABORTED, syscall.ECONNRESET, syscall.ETIMEDOUT, syscall.EHOSTDOWN, syscall.EHOSTUNREACH, syscall.EPIPE:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/signature"
+	"github.com/docker/distribution/registry/api/errcode"
+	errcodev2 "github.com/docker/distribution/registry/api/v2"
+	"github.com/hashicorp/go-multierror"
+)
+
+func TestBackoff(t *testing.T) {
+	options := &Options{Delay: time.Second, MaxDelay: 30 * time.Second}
+	for _, c := range []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{10, 15 * time.Second, 30 * time.Second},
+		{100, 15 * time.Second, 30 * time.Second},
+	} {
+		for range 20 {
+			if delay := options.backoff(c.attempt); delay < c.min || delay > c.max {
+				t.Errorf("attempt %d: %s, expected between %s and %s", c.attempt, delay, c.min, c.max)
+				break
+			}
+		}
+	}
+}
+
+func TestIsErrorRetryable(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{}}
+	refused.Err = syscall.ECONNREFUSED
+	for _, c := range []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("invalid manifest"), false},
+		{"canceled", fmt.Errorf("copying: %w", context.Canceled), false},
+		{"deadline", fmt.Errorf("copying: %w", context.DeadlineExceeded), false},
+		{"unauthorized", fmt.Errorf("reading manifest: %w", docker.ErrUnauthorizedForCredentials{Err: errors.New("bad password")}), false},
+		{"policy rejection", fmt.Errorf("copying: %w", signature.PolicyRequirementError("rejected")), false},
+		{"invalid signature", fmt.Errorf("verifying: %w", signature.InvalidSignatureError{}), false},
+		{"too many requests", fmt.Errorf("reading manifest: %w", docker.ErrTooManyRequests), true},
+		{"too many requests error code", errcode.ErrorCodeTooManyRequests.WithMessage("slow down"), true},
+		{"unexpected EOF", fmt.Errorf("reading blob: %w", io.ErrUnexpectedEOF), true},
+		{"denied", errcode.ErrorCodeDenied.WithMessage("denied"), false},
+		{"unknown manifest", errcodev2.ErrorCodeManifestUnknown.WithMessage("unknown"), false},
+		{"unknown name", fmt.Errorf("reading manifest: %w", errcodev2.ErrorCodeNameUnknown.WithMessage("unknown")), false},
+		{"unavailable", errcode.ErrorCodeUnavailable.WithMessage("maintenance"), true},
+		{"errors all retryable", errcode.Errors{errcode.ErrorCodeUnavailable.WithMessage("a"), errcode.ErrorCodeUnknown.WithMessage("b")}, true},
+		{"errors one final", errcode.Errors{errcode.ErrorCodeUnavailable.WithMessage("a"), errcode.ErrorCodeDenied.WithMessage("b")}, false},
+		{"errors empty", errcode.Errors{}, false},
+		{"multierror all retryable", multierror.Append(nil, io.ErrUnexpectedEOF, syscall.ECONNRESET), true},
+		{"multierror one final", multierror.Append(nil, io.ErrUnexpectedEOF, errors.New("invalid")), false},
+		{"wrapped multierror one final", fmt.Errorf("copying: %w", multierror.Append(nil, docker.ErrTooManyRequests, errors.New("invalid"))), false},
+		{"wrapped twice too many requests", fmt.Errorf("copying: %w", fmt.Errorf("reading manifest: %w", docker.ErrTooManyRequests)), true},
+		{"wrapped twice unexpected EOF", fmt.Errorf("copying: %w", fmt.Errorf("reading blob: %w", io.ErrUnexpectedEOF)), true},
+		{"connection refused", refused, true},
+		{"url EOF", &url.Error{Op: "Get", URL: "https://registry.example.com/v2/", Err: io.EOF}, true},
+		{"url connection reset", &url.Error{Op: "Get", URL: "https://registry.example.com/v2/", Err: syscall.ECONNRESET}, true},
+		{"url TLS failure", &url.Error{Op: "Get", URL: "https://registry.example.com/v2/", Err: errors.New("x509: certificate signed by unknown authority")}, false},
+		{"errno not retryable", syscall.ENOENT, false},
+		{"wrapped errno", fmt.Errorf("writing: %w", syscall.EPIPE), true},
+		{"status 500", errors.New("invalid status code from registry 500 (Internal Server Error)"), true},
+		{"status 502", errors.New("fetching blob: StatusCode: 502, <html>"), true},
+		{"status 503", errors.New("reading blob: received unexpected HTTP status: 503 Service Unavailable"), true},
+		{"status 408", errors.New("invalid status code from registry 408 (Request Timeout)"), true},
+		{"status 404", errors.New("invalid status code from registry 404 (Not Found)"), false},
+	} {
+		if res := IsErrorRetryable(c.err); res != c.expected {
+			t.Errorf("%s: %v, expected %v", c.name, res, c.expected)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/sirupsen/logrus"
+)
+
+// Flattener computes the merged filesystem of an image while its layers are visited from the bottom layer up,
+// and writes it as a single tar stream.
+//
+// Unlike Merger, it reads every layer twice: once for the headers, to know the final contents of every path,
+// and once to write the contents. This way directories can be written before the entries they contain,
+// and hard links keep the contents their target had in the layer of the link.
+type Flattener struct {
+	layers   int
+	entries  map[string]*node               // The merged filesystem, by path; implicit parent directories are missing
+	children map[string]map[string]struct{} // Paths of the entries in every directory, by directory path
+}
+
+// node is an entry of a layer.
+type node struct {
+	hdr      *tar.Header // Name (and Linkname of hard links) are cleaned paths relative to the root
+	position position
+	target   *node  // For hard links, the entry providing the contents
+	written  string // The path the contents of the entry were written to, if any
+}
+
+// position identifies an entry by its layer and its index in the tar stream of the layer, counting all entries.
+type position struct {
+	layer, index int
+}
+
+// NewFlattener returns a Flattener which has not read any layer yet.
+func NewFlattener() *Flattener {
+	return &Flattener{
+		entries:  map[string]*node{},
+		children: map[string]map[string]struct{}{},
+	}
+}
+
+// Layer reads the headers of the uncompressed tar stream r of the next layer, which must be the one just above
+// the previously read layer.
+func (f *Flattener) Layer(r io.Reader) error {
+	layer := f.layers
+	f.layers++
+	tr := tar.NewReader(r)
+	for index := 0; ; index++ {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := CleanPath(hdr.Name)
+		base := path.Base(name)
+
+		if strings.HasPrefix(base, archive.WhiteoutPrefix) {
+			// Whiteouts only apply to lower layers, not to entries of the same layer.
+			switch {
+			case base == archive.WhiteoutOpaqueDir:
+				for child := range f.children[path.Dir(name)] {
+					f.deleteBelow(child, layer)
+				}
+			case strings.HasPrefix(base, archive.WhiteoutMetaPrefix):
+				// AUFS metadata, e.g. .wh..wh.plnk and .wh..wh.aufs; not part of the filesystem.
+			default:
+				f.deleteBelow(path.Join(path.Dir(name), strings.TrimPrefix(base, archive.WhiteoutPrefix)), layer)
+			}
+			continue
+		}
+		if strings.HasPrefix(name, archive.WhiteoutMetaPrefix) {
+			continue // Contents of the AUFS metadata directories
+		}
+		if name == "." {
+			continue
+		}
+
+		hdr.Name = name
+		n := &node{hdr: hdr, position: position{layer: layer, index: index}}
+		if hdr.Typeflag == tar.TypeLink {
+			hdr.Linkname = CleanPath(hdr.Linkname)
+			target, ok := f.entries[hdr.Linkname]
+			if !ok {
+				logrus.Warnf("Skipping hard link %s, its target %s is not in the image", name, hdr.Linkname)
+				continue
+			}
+			if target.target != nil {
+				target = target.target
+			}
+			n.target = target
+		}
+		f.add(n)
+	}
+}
+
+// add adds n to the merged filesystem, replacing the entry at its path.
+func (f *Flattener) add(n *node) {
+	name := n.hdr.Name
+	if existing, ok := f.entries[name]; ok && !(existing.hdr.Typeflag == tar.TypeDir && n.hdr.Typeflag == tar.TypeDir) {
+		f.delete(name) // A directory replaced by something else loses its contents
+	}
+	// A parent replaced by a non-directory in a lower layer becomes a directory again.
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if parent, ok := f.entries[dir]; ok && parent.hdr.Typeflag != tar.TypeDir {
+			f.delete(dir)
+		}
+	}
+	f.entries[name] = n
+	for child, dir := name, path.Dir(name); child != "."; child, dir = dir, path.Dir(dir) {
+		if f.children[dir] == nil {
+			f.children[dir] = map[string]struct{}{}
+		}
+		f.children[dir][child] = struct{}{}
+	}
+}
+
+// delete removes p and its contents from the merged filesystem.
+func (f *Flattener) delete(p string) {
+	f.deleteBelow(p, f.layers)
+}
+
+// deleteBelow removes the entries at p, and in p, which come from layers below layer.
+func (f *Flattener) deleteBelow(p string, layer int) {
+	for child := range f.children[p] {
+		f.deleteBelow(child, layer)
+	}
+	if n, ok := f.entries[p]; ok && n.position.layer < layer {
+		delete(f.entries, p)
+	}
+	if len(f.children[p]) == 0 {
+		delete(f.children, p)
+		if _, ok := f.entries[p]; !ok {
+			delete(f.children[path.Dir(p)], p)
+		}
+	}
+}
+
+// Write writes the merged filesystem of the layers read by Layer to tw: directories first, parents before
+// their contents, then all other entries. open returns the uncompressed tar stream of a layer again,
+// by its index, counting from the bottom layer; it is only called for layers providing contents.
+//
+// A hard link whose target was replaced or deleted in an upper layer is written with the contents its target
+// had in the layer of the link.
+func (f *Flattener) Write(tw *tar.Writer, open func(layer int) (io.ReadCloser, error)) error {
+	var dirs []string
+	needed := map[position]*node{}
+	links := map[*node][]*node{} // Hard links, by the entry providing their contents
+	layers := map[int]struct{}{} // Layers providing any entry of needed
+	for p, n := range f.entries {
+		switch {
+		case n.hdr.Typeflag == tar.TypeDir:
+			dirs = append(dirs, p)
+			continue
+		case n.target != nil:
+			needed[n.target.position] = n.target
+			layers[n.target.position.layer] = struct{}{}
+			links[n.target] = append(links[n.target], n)
+		}
+		needed[n.position] = n
+		layers[n.position.layer] = struct{}{}
+	}
+	slices.Sort(dirs) // A parent sorts before its contents
+	for _, p := range dirs {
+		if err := tw.WriteHeader(f.entries[p].hdr); err != nil {
+			return err
+		}
+	}
+
+	for layer := range f.layers {
+		if _, ok := layers[layer]; !ok {
+			continue
+		}
+		if err := f.writeLayer(tw, layer, needed, links, open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLayer writes the entries of layer in needed.
+func (f *Flattener) writeLayer(tw *tar.Writer, layer int, needed map[position]*node, links map[*node][]*node,
+	open func(layer int) (io.ReadCloser, error)) error {
+	r, err := open(layer)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tr := tar.NewReader(r)
+	for index := 0; ; index++ {
+		if _, err := tr.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		n, ok := needed[position{layer: layer, index: index}]
+		if !ok {
+			continue
+		}
+		if n.target != nil {
+			if n.target.written == n.hdr.Name {
+				continue // The contents were written to the link itself
+			}
+			hdr := *n.hdr
+			hdr.Linkname = n.target.written
+			if err := tw.WriteHeader(&hdr); err != nil {
+				return err
+			}
+			continue
+		}
+		hdr := *n.hdr
+		if f.entries[hdr.Name] != n {
+			// Only the target of hard links: the contents are written to the first of them.
+			first := slices.MinFunc(links[n], func(a, b *node) int { return comparePositions(a.position, b.position) })
+			hdr.Name = first.hdr.Name
+		}
+		if err := tw.WriteHeader(&hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+		n.written = hdr.Name
+	}
+}
+
+func comparePositions(a, b position) int {
+	if a.layer != b.layer {
+		return a.layer - b.layer
+	}
+	return a.index - b.index
+}
@@ -0,0 +1,96 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestFlattener(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		layers   [][]string // Bottom layer first
+		expected []string
+	}{
+		{
+			name:     "directories first",
+			layers:   [][]string{{"b", "a/", "a/x"}, {"a/y", "c/"}},
+			expected: []string{"a/", "c/", "b", "a/x", "a/y"},
+		},
+		{
+			name:     "whiteout",
+			layers:   [][]string{{"a/", "a/x", "a/y"}, {"a/.wh.x", "a/z"}},
+			expected: []string{"a/", "a/y", "a/z"},
+		},
+		{
+			name:     "whiteout in the same layer",
+			layers:   [][]string{{"a/", "a/x"}, {"a/x", "a/.wh.x"}},
+			expected: []string{"a/", "a/x"},
+		},
+		{
+			name:     "opaque directory",
+			layers:   [][]string{{"a/", "a/x", "a/sub/", "a/sub/y"}, {"a/z", "a/.wh..wh..opq"}},
+			expected: []string{"a/", "a/z"},
+		},
+		{
+			name:     "directory replaced by a file",
+			layers:   [][]string{{"a/", "a/x"}, {"a"}},
+			expected: []string{"a"},
+		},
+		{
+			name:     "file replaced by a directory",
+			layers:   [][]string{{"a"}, {"a/", "a/x"}},
+			expected: []string{"a/", "a/x"},
+		},
+		{
+			name:     "AUFS metadata",
+			layers:   [][]string{{".wh..wh.plnk/", ".wh..wh.plnk/1", "x"}},
+			expected: []string{"x"},
+		},
+	} {
+		f := NewFlattener()
+		var blobs [][]byte
+		for _, names := range c.layers {
+			data, err := io.ReadAll(layer(t, names...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			blobs = append(blobs, data)
+			if err := f.Layer(bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		if err := f.Write(tw, func(i int) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(blobs[i])), nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		tr := tar.NewReader(&buf)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Typeflag == tar.TypeDir {
+				hdr.Name += "/"
+			}
+			names = append(names, hdr.Name)
+		}
+		if !slices.Equal(names, c.expected) {
+			t.Errorf("%s: written %q, expected %q", c.name, names, c.expected)
+		}
+	}
+}
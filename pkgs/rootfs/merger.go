@@ -0,0 +1,160 @@
+// Package rootfs merges the layers of a container image into the filesystem a container would see,
+// honouring OCI and AUFS whiteouts.
+package rootfs
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/containers/storage/pkg/archive"
+)
+
+// Entry is a layer entry passed to a WalkFunc.
+type Entry struct {
+	// Header of the entry; Name (and Linkname of hard links) are cleaned paths relative to the root,
+	// the root itself being ".".
+	Header *tar.Header
+	// Layer is the index of the layer the entry comes from, as passed to Merger.Layer.
+	Layer int
+	// Shadowed is true if an upper layer provides the same path, so this entry is not part of the
+	// merged filesystem. Lower directories are shadowed by upper ones, but their contents are not.
+	Shadowed bool
+}
+
+// WalkFunc is called for every entry of a layer which has not been deleted by an upper layer;
+// r reads the contents of the entry.
+// Returning ErrStop ends the walk without an error.
+type WalkFunc func(entry *Entry, r io.Reader) error
+
+// ErrStop can be returned by a WalkFunc to stop walking the current layer.
+var ErrStop = errors.New("stop walking")
+
+// Merger computes the merged filesystem of an image while its layers are visited from the topmost
+// layer down; this way reading can stop as soon as the interesting paths are known, and lower layers
+// are never downloaded.
+type Merger struct {
+	seen    map[string]bool     // Paths provided by visited layers; the value is true for directories
+	deleted map[string]struct{} // Paths whited out by visited layers
+	opaque  map[string]struct{} // Directories whose lower contents were hidden by visited layers
+}
+
+// NewMerger returns a Merger which has not visited any layer yet.
+func NewMerger() *Merger {
+	return &Merger{
+		seen:    map[string]bool{},
+		deleted: map[string]struct{}{},
+		opaque:  map[string]struct{}{},
+	}
+}
+
+// CleanPath returns p as a cleaned path relative to the root of the image, the root itself being ".".
+func CleanPath(p string) string {
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// Deleted returns true if p is not visible in any layer below the visited ones, because it, or one of
+// its parents, was whited out, or replaced by a non-directory.
+func (m *Merger) Deleted(p string) bool {
+	p = CleanPath(p)
+	if p == "." {
+		return false
+	}
+	if _, ok := m.deleted[p]; ok {
+		return true
+	}
+	for dir := path.Dir(p); ; dir = path.Dir(dir) {
+		if _, ok := m.deleted[dir]; ok {
+			return true
+		}
+		if _, ok := m.opaque[dir]; ok {
+			return true
+		}
+		if isDir, ok := m.seen[dir]; ok && !isDir {
+			return true
+		}
+		if dir == "." {
+			return false
+		}
+	}
+}
+
+// Seen returns true if p is provided by one of the visited layers.
+func (m *Merger) Seen(p string) bool {
+	_, ok := m.seen[CleanPath(p)]
+	return ok
+}
+
+// Layer walks the uncompressed tar stream r of the next layer, which must be the one just below the
+// previously visited layer, and calls fn for its entries which are not deleted by upper layers.
+// Whiteout markers are consumed by the Merger and not passed to fn.
+func (m *Merger) Layer(index int, r io.Reader, fn WalkFunc) error {
+	seen := map[string]bool{}
+	deleted := map[string]struct{}{}
+	opaque := map[string]struct{}{}
+	// Whatever this layer adds or deletes only applies to lower layers, so the state is updated
+	// only after the whole layer was read; that also happens if fn stops the walk early.
+	defer func() {
+		for p, isDir := range seen {
+			m.seen[p] = isDir
+		}
+		for p := range deleted {
+			m.deleted[p] = struct{}{}
+		}
+		for p := range opaque {
+			m.opaque[p] = struct{}{}
+		}
+	}()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := CleanPath(hdr.Name)
+		base := path.Base(name)
+
+		if strings.HasPrefix(base, archive.WhiteoutPrefix) {
+			switch {
+			case base == archive.WhiteoutOpaqueDir:
+				opaque[path.Dir(name)] = struct{}{}
+			case strings.HasPrefix(base, archive.WhiteoutMetaPrefix):
+				// AUFS metadata, e.g. .wh..wh.plnk and .wh..wh.aufs; not part of the filesystem.
+			default:
+				deleted[path.Join(path.Dir(name), strings.TrimPrefix(base, archive.WhiteoutPrefix))] = struct{}{}
+			}
+			continue
+		}
+		if strings.HasPrefix(name, archive.WhiteoutMetaPrefix) {
+			continue // Contents of the AUFS metadata directories
+		}
+		if m.Deleted(name) {
+			continue
+		}
+
+		hdr.Name = name
+		if hdr.Typeflag == tar.TypeLink {
+			hdr.Linkname = CleanPath(hdr.Linkname)
+		}
+		_, shadowed := m.seen[name]
+		if !shadowed {
+			seen[name] = hdr.Typeflag == tar.TypeDir
+		}
+		if err := fn(&Entry{Header: hdr, Layer: index, Shadowed: shadowed}, tr); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+}
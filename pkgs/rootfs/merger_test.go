@@ -0,0 +1,209 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// layer returns a tar stream with an entry for each of names: directories end with "/", symbolic links are
+// "NAME -> TARGET", anything else is a regular file.
+func layer(t *testing.T, names ...string) io.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Typeflag: tar.TypeReg}
+		switch {
+		case strings.HasSuffix(name, "/"):
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0o755
+		case strings.Contains(name, " -> "):
+			hdr.Name, hdr.Linkname, _ = strings.Cut(name, " -> ")
+			hdr.Typeflag = tar.TypeSymlink
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestMergerLayers(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		layers   [][]string // Topmost first
+		expected []string   // Paths of the merged filesystem, as LAYER:PATH, in walk order
+	}{
+		{
+			name: "file whiteout",
+			layers: [][]string{
+				{"etc/", "etc/.wh.passwd"},
+				{"etc/", "etc/passwd", "etc/group"},
+			},
+			expected: []string{"0:etc", "1:etc/group"},
+		},
+		{
+			name: "directory whiteout hides the subtree",
+			layers: [][]string{
+				{".wh.opt"},
+				{"opt/", "opt/app/", "opt/app/bin", "usr/"},
+			},
+			expected: []string{"1:usr"},
+		},
+		{
+			name: "opaque directory",
+			layers: [][]string{
+				{"var/", "var/lib/", "var/lib/.wh..wh..opq", "var/lib/new"},
+				{"var/", "var/lib/", "var/lib/old", "var/log/"},
+			},
+			expected: []string{"0:var", "0:var/lib", "0:var/lib/new", "1:var/log"},
+		},
+		{
+			name: "opaque directory hides lower subdirectories",
+			layers: [][]string{
+				{"data/", "data/.wh..wh..opq"},
+				{"data/", "data/a/", "data/a/b"},
+			},
+			expected: []string{"0:data"},
+		},
+		{
+			name: "whiteout only applies to lower layers",
+			layers: [][]string{
+				{"x/", "x/.wh.f", "x/f"},
+				{"x/", "x/f", "x/g"},
+			},
+			expected: []string{"0:x", "0:x/f", "1:x/g"},
+		},
+		{
+			name: "directory replaced by a file",
+			layers: [][]string{
+				{"srv"},
+				{"srv/", "srv/index.html"},
+			},
+			expected: []string{"0:srv"},
+		},
+		{
+			name: "directory replaced by a symbolic link",
+			layers: [][]string{
+				{"lib -> usr/lib"},
+				{"lib/", "lib/libc.so"},
+			},
+			expected: []string{"0:lib"},
+		},
+		{
+			name: "whiteouts accumulate across layers",
+			layers: [][]string{
+				{".wh.a"},
+				{".wh.b"},
+				{"a", "b", "c"},
+			},
+			expected: []string{"2:c"},
+		},
+		{
+			name: "AUFS metadata is ignored",
+			layers: [][]string{
+				{".wh..wh.aufs", ".wh..wh.plnk/", ".wh..wh.plnk/1234.5678", "f"},
+				{"f", "g"},
+			},
+			expected: []string{"0:f", "1:g"},
+		},
+		{
+			name: "paths are cleaned",
+			layers: [][]string{
+				{"./etc/./.wh.hosts"},
+				{"./etc/", "etc/hosts", "/etc/resolv.conf"},
+			},
+			expected: []string{"1:etc", "1:etc/resolv.conf"},
+		},
+	} {
+		m := NewMerger()
+		var res []string
+		for i, names := range c.layers {
+			if err := m.Layer(i, layer(t, names...), func(entry *Entry, _ io.Reader) error {
+				if !entry.Shadowed {
+					res = append(res, string(rune('0'+entry.Layer))+":"+entry.Header.Name)
+				}
+				return nil
+			}); err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+		}
+		if !slices.Equal(res, c.expected) {
+			t.Errorf("%s: %v, expected %v", c.name, res, c.expected)
+		}
+	}
+}
+
+func TestMergerDeleted(t *testing.T) {
+	m := NewMerger()
+	if err := m.Layer(0, layer(t, "a/.wh.b", "c/.wh..wh..opq", "c/d", "e"), func(*Entry, io.Reader) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	for p, expected := range map[string]bool{
+		".":     false,
+		"a":     false,
+		"a/b":   true,
+		"a/b/c": true,
+		"/a/b":  true,
+		"a/x":   false,
+		"c":     false,
+		"c/d":   true, // Provided by the visited layer, so any lower c/d is hidden
+		"c/z":   true,
+		"e":     false,
+		"e/f":   true, // e is a file
+	} {
+		if deleted := m.Deleted(p); deleted != expected {
+			t.Errorf("Deleted(%q) = %v, expected %v", p, deleted, expected)
+		}
+	}
+	for p, expected := range map[string]bool{"c/d": true, "e": true, "a/b": false, "./e": true} {
+		if seen := m.Seen(p); seen != expected {
+			t.Errorf("Seen(%q) = %v, expected %v", p, seen, expected)
+		}
+	}
+}
+
+func TestMergerStop(t *testing.T) {
+	m := NewMerger()
+	var walked []string
+	err := m.Layer(0, layer(t, "a", ".wh.b", "c", "d"), func(entry *Entry, _ io.Reader) error {
+		walked = append(walked, entry.Header.Name)
+		if entry.Header.Name == "c" {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(walked, []string{"a", "c"}) {
+		t.Errorf("walked %v, expected [a c]", walked)
+	}
+	// What was read before stopping applies to lower layers.
+	if !m.Deleted("b") || !m.Seen("c") || m.Seen("d") {
+		t.Errorf("state after stopping: b deleted %v, c seen %v, d seen %v", m.Deleted("b"), m.Seen("c"), m.Seen("d"))
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	for p, expected := range map[string]string{
+		"":             ".",
+		"/":            ".",
+		".":            ".",
+		"./":           ".",
+		"etc/passwd":   "etc/passwd",
+		"/etc/passwd":  "etc/passwd",
+		"./etc//x/../": "etc",
+		"../../etc":    "etc",
+	} {
+		if res := CleanPath(p); res != expected {
+			t.Errorf("CleanPath(%q) = %q, expected %q", p, res, expected)
+		}
+	}
+}
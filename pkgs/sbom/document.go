@@ -0,0 +1,246 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gopull/pkgs/i18n"
+)
+
+// Supported document formats.
+const (
+	FormatSPDXJSON  = "spdx-json"
+	FormatCycloneDX = "cyclonedx"
+)
+
+// Document is a bill of materials for an image.
+type Document struct {
+	Name     string // Name of the image, as given by the user
+	Digest   string // Manifest digest, if known
+	Created  time.Time
+	Tool     string // Name and version of the generating tool
+	Distro   Distro
+	Packages []Package
+}
+
+// CheckFormat returns an error if format is not a supported document format.
+func CheckFormat(format string) error {
+	switch format {
+	case FormatSPDXJSON, FormatCycloneDX, "cyclonedx-json":
+		return nil
+	}
+	return i18n.Errorf("unknown SBOM format %q, expected %s or %s", format, FormatSPDXJSON, FormatCycloneDX)
+}
+
+// Write writes d in format, one of the Format* constants, to w.
+func (d *Document) Write(w io.Writer, format string) error {
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+	var doc any
+	if format == FormatSPDXJSON {
+		doc = d.spdx()
+	} else {
+		doc = d.cycloneDX()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(doc)
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	LicenseComments       string            `json:"licenseComments,omitempty"`
+	SourceInfo            string            `json:"sourceInfo,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxIDInvalidChars matches what may not appear in an SPDX identifier.
+var spdxIDInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// spdx returns d as an SPDX 2.3 document.
+func (d *Document) spdx() spdxDocument {
+	const imageID = "SPDXRef-Image"
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              d.Name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/gopull-" + spdxIDInvalidChars.ReplaceAllString(d.Name, "-") + "-" + uuid.NewString(),
+		CreationInfo: spdxCreationInfo{
+			Created:  d.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + d.Tool},
+		},
+		Packages: []spdxPackage{{
+			Name:                  d.Name,
+			SPDXID:                imageID,
+			VersionInfo:           d.Digest,
+			DownloadLocation:      "NOASSERTION",
+			LicenseConcluded:      "NOASSERTION",
+			LicenseDeclared:       "NOASSERTION",
+			PrimaryPackagePurpose: "CONTAINER",
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: imageID,
+		}},
+	}
+	for i, pkg := range d.Packages {
+		id := fmt.Sprintf("SPDXRef-Package-%s-%d-%s", pkg.Type, i+1, spdxIDInvalidChars.ReplaceAllString(pkg.Name, "-"))
+		p := spdxPackage{
+			Name:             pkg.Name,
+			SPDXID:           id,
+			VersionInfo:      pkg.Version,
+			DownloadLocation: "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			// Package managers record licenses in free form, which is not necessarily a valid SPDX expression.
+			LicenseDeclared: "NOASSERTION",
+			SourceInfo:      "found in /" + pkg.Location,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  pkg.PURL,
+			}},
+		}
+		if pkg.License != "" {
+			p.LicenseComments = "Declared license: " + pkg.License
+		}
+		doc.Packages = append(doc.Packages, p)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      imageID,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: id,
+		})
+	}
+	return doc
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cycloneDXComponent `json:"components"`
+	} `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Type       string              `json:"type"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Licenses   []cycloneDXLicense  `json:"licenses,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXLicense struct {
+	License struct {
+		Name string `json:"name"`
+	} `json:"license"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// cycloneDX returns d as a CycloneDX 1.5 document.
+func (d *Document) cycloneDX() cycloneDXDocument {
+	doc := cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Components:   []cycloneDXComponent{},
+	}
+	doc.Metadata.Timestamp = d.Created.UTC().Format(time.RFC3339)
+	toolName, toolVersion, _ := strings.Cut(d.Tool, " ")
+	doc.Metadata.Tools.Components = []cycloneDXComponent{{Type: "application", Name: toolName, Version: toolVersion}}
+	doc.Metadata.Component = cycloneDXComponent{
+		BOMRef:  d.Name,
+		Type:    "container",
+		Name:    d.Name,
+		Version: d.Digest,
+	}
+
+	if d.Distro.ID != "" {
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			BOMRef:  "os:" + d.Distro.ID,
+			Type:    "operating-system",
+			Name:    d.Distro.ID,
+			Version: d.Distro.VersionID,
+		})
+	}
+	for _, pkg := range d.Packages {
+		c := cycloneDXComponent{
+			BOMRef:  pkg.PURL,
+			Type:    "library",
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			PURL:    pkg.PURL,
+			Properties: []cycloneDXProperty{
+				{Name: "gopull:package:type", Value: pkg.Type},
+				{Name: "gopull:location", Value: "/" + pkg.Location},
+			},
+		}
+		if pkg.License != "" {
+			var l cycloneDXLicense
+			l.License.Name = pkg.License
+			c.Licenses = []cycloneDXLicense{l}
+		}
+		doc.Components = append(doc.Components, c)
+	}
+	return doc
+}
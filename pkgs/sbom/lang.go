@@ -0,0 +1,250 @@
+package sbom
+
+import (
+	"debug/buildinfo"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// readGoBinary reads the build information embedded by the Go toolchain into the executable read from r.
+// Executables which were not built by Go, or without module support, contain no packages.
+// The build information is found through the section headers, which needs random access; rather than holding
+// the executable in memory, it is copied to a temporary file in tmpDir.
+func readGoBinary(tmpDir string, r io.Reader) (_ []Package, retErr error) {
+	f, err := os.CreateTemp(tmpDir, "gopull-sbom-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+		if err := os.Remove(f.Name()); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	if _, err := io.Copy(f, r); err != nil {
+		return nil, err
+	}
+	info, err := buildinfo.Read(f)
+	if err != nil {
+		return nil, nil
+	}
+	res := []Package{{
+		Type:    TypeGolang,
+		Name:    "stdlib",
+		Version: info.GoVersion,
+	}}
+	if info.Main.Path != "" {
+		main := Package{Type: TypeGolang, Name: info.Main.Path}
+		if info.Main.Version != "(devel)" {
+			main.Version = info.Main.Version
+		}
+		res = append(res, main)
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		res = append(res, Package{
+			Type:    TypeGolang,
+			Name:    dep.Path,
+			Version: dep.Version,
+		})
+	}
+	return res, nil
+}
+
+// npmLockDependency is an entry of the "dependencies" tree of a lockfileVersion 1 package-lock.json.
+type npmLockDependency struct {
+	Version      string                       `json:"version"`
+	Dependencies map[string]npmLockDependency `json:"dependencies"`
+}
+
+// parsePackageLock parses an npm package-lock.json, or the node_modules/.package-lock.json written by npm 7 and later.
+func parsePackageLock(_ string, data []byte) ([]Package, error) {
+	var lock struct {
+		Packages map[string]struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			Link    bool   `json:"link"`
+			License any    `json:"license"`
+		} `json:"packages"` // lockfileVersion 2 and 3
+		Dependencies map[string]npmLockDependency `json:"dependencies"` // lockfileVersion 1 and 2
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	var res []Package
+	if len(lock.Packages) != 0 {
+		for key, entry := range lock.Packages {
+			i := strings.LastIndex(key, "node_modules/")
+			if i < 0 || entry.Link {
+				continue // The root project, workspaces, and links to them
+			}
+			name := entry.Name
+			if name == "" {
+				name = key[i+len("node_modules/"):]
+			}
+			license, _ := entry.License.(string)
+			res = append(res, Package{
+				Type:    TypeNpm,
+				Name:    name,
+				Version: entry.Version,
+				License: license,
+			})
+		}
+		return res, nil
+	}
+
+	var walk func(deps map[string]npmLockDependency)
+	walk = func(deps map[string]npmLockDependency) {
+		for name, dep := range deps {
+			res = append(res, Package{
+				Type:    TypeNpm,
+				Name:    name,
+				Version: dep.Version,
+			})
+			walk(dep.Dependencies)
+		}
+	}
+	walk(lock.Dependencies)
+	return res, nil
+}
+
+// parseYarnLock parses a yarn.lock, in either the Yarn 1 or the YAML-based Yarn 2+ format.
+func parseYarnLock(_ string, data []byte) ([]Package, error) {
+	var res []Package
+	name := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case line == "" || line[0] == '#':
+			continue
+		case line[0] != ' ':
+			// A header, listing the specifiers resolved to the entry: `"lodash@^4.17.0", lodash@^4.17.21:`
+			spec, _, _ := strings.Cut(strings.TrimSuffix(line, ":"), ",")
+			spec = strings.Trim(spec, `"`)
+			name = ""
+			// The name ends at the first "@" which does not start a scope; Yarn 2+ writes "name@npm:range".
+			if i := strings.Index(spec[min(1, len(spec)):], "@"); i >= 0 && !strings.Contains(spec, "@workspace:") {
+				name = spec[:i+1]
+			}
+		case name != "" && strings.HasPrefix(strings.TrimSpace(line), "version"):
+			version := strings.TrimPrefix(strings.TrimSpace(line), "version")
+			version = strings.Trim(strings.TrimPrefix(strings.TrimSpace(version), ":"), ` "`)
+			res = append(res, Package{
+				Type:    TypeNpm,
+				Name:    name,
+				Version: version,
+			})
+			name = ""
+		}
+	}
+	return res, nil
+}
+
+// parsePipfileLock parses a Pipfile.lock written by pipenv; development packages are included.
+func parsePipfileLock(_ string, data []byte) ([]Package, error) {
+	type section map[string]struct {
+		Version string `json:"version"`
+	}
+	var lock struct {
+		Default section `json:"default"`
+		Develop section `json:"develop"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	var res []Package
+	for _, s := range []section{lock.Default, lock.Develop} {
+		for name, entry := range s {
+			res = append(res, Package{
+				Type:    TypePyPI,
+				Name:    name,
+				Version: strings.TrimPrefix(entry.Version, "=="),
+			})
+		}
+	}
+	return res, nil
+}
+
+// parsePoetryLock parses a poetry.lock.
+func parsePoetryLock(_ string, data []byte) ([]Package, error) {
+	var lock struct {
+		Package []struct {
+			Name    string `toml:"name"`
+			Version string `toml:"version"`
+		} `toml:"package"`
+	}
+	if err := toml.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	var res []Package
+	for _, entry := range lock.Package {
+		res = append(res, Package{
+			Type:    TypePyPI,
+			Name:    entry.Name,
+			Version: entry.Version,
+		})
+	}
+	return res, nil
+}
+
+// parseRequirements parses a pip requirements file; only exactly pinned ("==") requirements are recorded,
+// anything else does not tell which version is installed.
+func parseRequirements(_ string, data []byte) ([]Package, error) {
+	var res []Package
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		line, _, _ = strings.Cut(line, ";") // Environment markers
+		line = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), `\`))
+		if line == "" || line[0] == '-' {
+			continue // Options, including --hash continuation lines
+		}
+		name, version, ok := strings.Cut(line, "==")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, "[") // Extras
+		version, _, _ = strings.Cut(strings.TrimSpace(version), " ")
+		res = append(res, Package{
+			Type:    TypePyPI,
+			Name:    strings.TrimSpace(name),
+			Version: strings.TrimPrefix(version, "="), // "===" is an arbitrary equality
+		})
+	}
+	return res, nil
+}
+
+// parsePythonMetadata parses the METADATA file of an installed Python distribution (a .dist-info directory).
+func parsePythonMetadata(_ string, data []byte) ([]Package, error) {
+	pkg := Package{Type: TypePyPI}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			break // The description follows the headers
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			pkg.Name = value
+		case "Version":
+			pkg.Version = value
+		case "License", "License-Expression":
+			if pkg.License == "" || key == "License-Expression" {
+				pkg.License = value
+			}
+		}
+	}
+	return []Package{pkg}, nil
+}
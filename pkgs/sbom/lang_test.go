@@ -0,0 +1,29 @@
+package sbom
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadGoBinary(t *testing.T) {
+	// The test binary is a Go executable.
+	f, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	pkgs, err := readGoBinary(t.TempDir(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) == 0 || pkgs[0].Name != "stdlib" || pkgs[0].Version != runtime.Version() {
+		t.Errorf("packages %+v, expected stdlib %s first", pkgs, runtime.Version())
+	}
+
+	pkgs, err = readGoBinary(t.TempDir(), strings.NewReader("\x7fELF not really"))
+	if err != nil || len(pkgs) != 0 {
+		t.Errorf("packages %+v, error %v for a non-Go executable, expected none", pkgs, err)
+	}
+}
@@ -0,0 +1,201 @@
+package sbom
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite" // Registers the "sqlite" backend for database/sql; pure Go, so that it works without cgo
+)
+
+// parseControlParagraphs parses the deb822-like format used by dpkg and, with single-letter keys, apk.
+// Continuation lines are ignored, only the first line of each field is kept.
+func parseControlParagraphs(data []byte, sep string) []map[string]string {
+	var res []map[string]string
+	current := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			if len(current) != 0 {
+				res = append(res, current)
+				current = map[string]string{}
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, sep)
+		if ok {
+			current[key] = strings.TrimSpace(value)
+		}
+	}
+	if len(current) != 0 {
+		res = append(res, current)
+	}
+	return res
+}
+
+// parseDpkgStatus parses /var/lib/dpkg/status, and the per-package files in /var/lib/dpkg/status.d used by distroless images.
+func parseDpkgStatus(_ string, data []byte) ([]Package, error) {
+	var res []Package
+	for _, fields := range parseControlParagraphs(data, ":") {
+		// Packages which were removed but not purged are still listed.
+		if status, ok := fields["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+		res = append(res, Package{
+			Type:    TypeDeb,
+			Name:    fields["Package"],
+			Version: fields["Version"],
+			Arch:    fields["Architecture"],
+			License: fields["License"],
+		})
+	}
+	return res, nil
+}
+
+// parseApkInstalled parses the Alpine /lib/apk/db/installed database.
+func parseApkInstalled(_ string, data []byte) ([]Package, error) {
+	var res []Package
+	for _, fields := range parseControlParagraphs(data, ":") {
+		res = append(res, Package{
+			Type:    TypeApk,
+			Name:    fields["P"],
+			Version: fields["V"],
+			Arch:    fields["A"],
+			License: fields["L"],
+		})
+	}
+	return res, nil
+}
+
+// RPM header tags and types, see rpmtag.h.
+const (
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagRelease = 1002
+	rpmTagEpoch   = 1003
+	rpmTagLicense = 1014
+	rpmTagArch    = 1022
+
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+)
+
+// parseRPMDB reads the packages from an rpmdb.sqlite database, as used since Fedora 33 and RHEL 9.
+// The older BerkeleyDB and NDB formats are not supported.
+func parseRPMDB(tmpDir string, data []byte) (_ []Package, retErr error) {
+	// SQLite needs a file to work with, created in tmpDir.
+	dir, err := os.MkdirTemp(tmpDir, "gopull-rpmdb")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, "rpmdb.sqlite")
+	if err := os.WriteFile(dbPath, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?mode=ro&immutable=1")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := db.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	rows, err := db.Query("SELECT blob FROM Packages")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []Package
+	for rows.Next() {
+		var blob []byte
+		if err := rows.Scan(&blob); err != nil {
+			return nil, err
+		}
+		pkg, err := parseRPMHeader(blob)
+		if err != nil {
+			return nil, err
+		}
+		if pkg.Name == "gpg-pubkey" {
+			continue // Imported signing keys, not software
+		}
+		res = append(res, pkg)
+	}
+	return res, rows.Err()
+}
+
+// parseRPMHeader parses an RPM header blob, as stored in the rpm database:
+// the index entry count and data length, the index entries, and the data store.
+func parseRPMHeader(blob []byte) (Package, error) {
+	errInvalid := errors.New("invalid RPM header")
+	if len(blob) < 8 {
+		return Package{}, errInvalid
+	}
+	indexCount := int(binary.BigEndian.Uint32(blob[0:4]))
+	dataLength := int(binary.BigEndian.Uint32(blob[4:8]))
+	dataStart := 8 + 16*indexCount
+	if indexCount < 0 || dataLength < 0 || dataStart+dataLength > len(blob) {
+		return Package{}, errInvalid
+	}
+	store := blob[dataStart : dataStart+dataLength]
+
+	pkg := Package{Type: TypeRPM}
+	release := ""
+	for i := 0; i < indexCount; i++ {
+		entry := blob[8+16*i : 8+16*(i+1)]
+		tag := binary.BigEndian.Uint32(entry[0:4])
+		typ := binary.BigEndian.Uint32(entry[4:8])
+		offset := int(binary.BigEndian.Uint32(entry[8:12]))
+		if offset < 0 || offset >= len(store) {
+			continue
+		}
+		var value string
+		switch typ {
+		case rpmTypeString, rpmTypeStringArray, rpmTypeI18NString:
+			// For arrays, only the first (untranslated) string is used.
+			end := bytes.IndexByte(store[offset:], 0)
+			if end < 0 {
+				return Package{}, errInvalid
+			}
+			value = string(store[offset : offset+end])
+		case rpmTypeInt32:
+			if offset+4 > len(store) {
+				return Package{}, errInvalid
+			}
+			value = fmt.Sprint(binary.BigEndian.Uint32(store[offset : offset+4]))
+		default:
+			continue
+		}
+		switch tag {
+		case rpmTagName:
+			pkg.Name = value
+		case rpmTagVersion:
+			pkg.Version = value
+		case rpmTagRelease:
+			release = value
+		case rpmTagEpoch:
+			pkg.Epoch = value
+		case rpmTagLicense:
+			pkg.License = value
+		case rpmTagArch:
+			pkg.Arch = value
+		}
+	}
+	if release != "" {
+		pkg.Version += "-" + release
+	}
+	return pkg, nil
+}
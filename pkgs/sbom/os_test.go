@@ -0,0 +1,135 @@
+package sbom
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// rpmHeader returns an RPM header blob with the string tags in tags.
+func rpmHeader(tags map[uint32]string) []byte {
+	var index, store bytes.Buffer
+	for _, tag := range []uint32{rpmTagName, rpmTagVersion, rpmTagRelease, rpmTagEpoch, rpmTagLicense, rpmTagArch} {
+		value, ok := tags[tag]
+		if !ok {
+			continue
+		}
+		_ = binary.Write(&index, binary.BigEndian, []uint32{tag, rpmTypeString, uint32(store.Len()), 1})
+		store.WriteString(value)
+		store.WriteByte(0)
+	}
+	var res bytes.Buffer
+	_ = binary.Write(&res, binary.BigEndian, []uint32{uint32(index.Len() / 16), uint32(store.Len())})
+	res.Write(index.Bytes())
+	res.Write(store.Bytes())
+	return res.Bytes()
+}
+
+func TestParseRPMDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpmdb.sqlite")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE Packages (hnum INTEGER PRIMARY KEY AUTOINCREMENT, blob BLOB NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tags := range []map[uint32]string{
+		{rpmTagName: "bash", rpmTagVersion: "5.1.8", rpmTagRelease: "9.el9", rpmTagArch: "x86_64", rpmTagLicense: "GPLv3+"},
+		{rpmTagName: "gpg-pubkey", rpmTagVersion: "fd431d51", rpmTagRelease: "4ae0493b"},
+	} {
+		if _, err := db.Exec("INSERT INTO Packages (blob) VALUES (?)", rpmHeader(tags)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := parseRPMDB(t.TempDir(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Package{Type: TypeRPM, Name: "bash", Version: "5.1.8-9.el9", Arch: "x86_64", License: "GPLv3+"}
+	if len(pkgs) != 1 || pkgs[0] != expected {
+		t.Errorf("packages %+v, expected %+v", pkgs, expected)
+	}
+}
+
+func TestParseDpkgStatus(t *testing.T) {
+	data := []byte(`Package: base-files
+Status: install ok installed
+Architecture: amd64
+Version: 12.4+deb12u5
+Description: Debian base system miscellaneous files
+ This package contains the basic filesystem hierarchy.
+ .
+ Continuation: lines are not fields
+
+Package: oldpkg
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0
+
+Package: tzdata
+Status: install ok installed
+Architecture: all
+Version: 2024a-0+deb12u1
+`)
+	pkgs, err := parseDpkgStatus("var/lib/dpkg/status", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Package{
+		{Type: TypeDeb, Name: "base-files", Version: "12.4+deb12u5", Arch: "amd64"},
+		{Type: TypeDeb, Name: "tzdata", Version: "2024a-0+deb12u1", Arch: "all"},
+	}
+	if !slices.Equal(pkgs, expected) {
+		t.Errorf("packages %+v, expected %+v", pkgs, expected)
+	}
+
+	// The files in /var/lib/dpkg/status.d of distroless images have no Status field.
+	pkgs, err = parseDpkgStatus("var/lib/dpkg/status.d/libc6", []byte("Package: libc6\r\nVersion: 2.36-9\r\nArchitecture: arm64\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Package{Type: TypeDeb, Name: "libc6", Version: "2.36-9", Arch: "arm64"}); len(pkgs) != 1 || pkgs[0] != want {
+		t.Errorf("packages %+v, expected %+v", pkgs, want)
+	}
+}
+
+func TestParseApkInstalled(t *testing.T) {
+	data := []byte(`C:Q1abc=
+P:musl
+V:1.2.4-r2
+A:x86_64
+L:MIT
+F:lib
+R:ld-musl-x86_64.so.1
+
+
+P:busybox
+V:1.36.1-r15
+A:x86_64
+L:GPL-2.0-only
+`)
+	pkgs, err := parseApkInstalled("lib/apk/db/installed", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Package{
+		{Type: TypeApk, Name: "musl", Version: "1.2.4-r2", Arch: "x86_64", License: "MIT"},
+		{Type: TypeApk, Name: "busybox", Version: "1.36.1-r15", Arch: "x86_64", License: "GPL-2.0-only"},
+	}
+	if !slices.Equal(pkgs, expected) {
+		t.Errorf("packages %+v, expected %+v", pkgs, expected)
+	}
+}
@@ -0,0 +1,266 @@
+// Package sbom builds a software bill of materials from the files of a container image,
+// by reading package databases, lock files and Go build information; no network access is needed.
+package sbom
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"gopull/pkgs/i18n"
+)
+
+// Package types, as used in package URLs.
+const (
+	TypeDeb    = "deb"
+	TypeApk    = "apk"
+	TypeRPM    = "rpm"
+	TypeGolang = "golang"
+	TypePyPI   = "pypi"
+	TypeNpm    = "npm"
+)
+
+// maxFileSize limits how much of a single file is read; package databases are much smaller,
+// and larger executables are not worth scanning for Go build information.
+// Only package databases and lock files are read into memory; executables are copied to a temporary file.
+const maxFileSize = 256 << 20
+
+// Package is a software package found in an image.
+type Package struct {
+	Type     string // One of the Type* constants
+	Name     string
+	Version  string
+	Arch     string
+	Epoch    string // rpm only
+	License  string // As recorded by the package manager, not necessarily an SPDX expression
+	Location string // Path of the file the package was found in, relative to the image root
+	PURL     string
+}
+
+// Distro identifies the Linux distribution of an image, from os-release.
+type Distro struct {
+	ID        string
+	VersionID string
+	Name      string
+}
+
+// Collector accumulates the packages found in the files of an image.
+// Files are passed to Add; Wants tells which files are worth reading.
+type Collector struct {
+	tmpDir    string // For temporary copies of files needing random access; the default directory if empty
+	packages  []Package
+	osRelease map[string]Distro // By path, /etc/os-release takes precedence over /usr/lib/os-release
+}
+
+// NewCollector returns an empty Collector, writing temporary files to tmpDir, or to the default directory
+// for temporary files if tmpDir is empty.
+func NewCollector(tmpDir string) *Collector {
+	return &Collector{tmpDir: tmpDir, osRelease: map[string]Distro{}}
+}
+
+// parser extracts packages from the contents of the file at p.
+type parser func(p string, data []byte) ([]Package, error)
+
+// parserFor returns the parser for the file at p, a cleaned path relative to the image root, or nil.
+// Parsers needing temporary files write them to tmpDir.
+func parserFor(p string, tmpDir string) parser {
+	base := path.Base(p)
+	switch {
+	case p == "var/lib/dpkg/status" || path.Dir(p) == "var/lib/dpkg/status.d" && !strings.HasSuffix(base, ".md5sums"):
+		return parseDpkgStatus
+	case p == "lib/apk/db/installed":
+		return parseApkInstalled
+	case base == "rpmdb.sqlite" && (path.Dir(p) == "var/lib/rpm" || path.Dir(p) == "usr/lib/sysimage/rpm"):
+		return func(_ string, data []byte) ([]Package, error) {
+			return parseRPMDB(tmpDir, data)
+		}
+	case base == "package-lock.json" || base == ".package-lock.json":
+		return parsePackageLock
+	case base == "yarn.lock":
+		return parseYarnLock
+	case base == "Pipfile.lock":
+		return parsePipfileLock
+	case base == "poetry.lock":
+		return parsePoetryLock
+	case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+		return parseRequirements
+	case base == "METADATA" && strings.HasSuffix(path.Dir(p), ".dist-info"):
+		return parsePythonMetadata
+	}
+	return nil
+}
+
+// Wants returns true if the regular file at p, a cleaned path relative to the image root, with mode and size,
+// may contain package information and should be passed to Add.
+func Wants(p string, mode fs.FileMode, size int64) bool {
+	if size > maxFileSize {
+		return false
+	}
+	if p == "etc/os-release" || p == "usr/lib/os-release" || parserFor(p, "") != nil {
+		return true
+	}
+	// Possibly a Go binary; Add checks for an executable format before reading all of it.
+	return mode&0o111 != 0 && size > 0
+}
+
+// Add reads the file at p, a cleaned path relative to the image root, and records the packages it describes.
+func (c *Collector) Add(p string, r io.Reader) error {
+	if p == "etc/os-release" || p == "usr/lib/os-release" {
+		data, err := io.ReadAll(io.LimitReader(r, maxFileSize))
+		if err != nil {
+			return err
+		}
+		c.osRelease[p] = parseOSRelease(data)
+		return nil
+	}
+
+	var pkgs []Package
+	if parse := parserFor(p, c.tmpDir); parse != nil {
+		data, err := io.ReadAll(io.LimitReader(r, maxFileSize))
+		if err != nil {
+			return err
+		}
+		pkgs, err = parse(p, data)
+		if err != nil {
+			return i18n.Errorf("parsing %s: %w", p, err)
+		}
+	} else {
+		br := bufio.NewReader(r)
+		magic, err := br.Peek(4)
+		if err != nil || !isExecutable(magic) {
+			return nil
+		}
+		pkgs, err = readGoBinary(c.tmpDir, io.LimitReader(br, maxFileSize))
+		if err != nil {
+			return i18n.Errorf("reading %s: %w", p, err)
+		}
+	}
+	for i := range pkgs {
+		pkgs[i].Location = p
+	}
+	c.packages = append(c.packages, pkgs...)
+	return nil
+}
+
+// Distro returns the distribution of the image, if any os-release file was found.
+func (c *Collector) Distro() Distro {
+	if d, ok := c.osRelease["etc/os-release"]; ok {
+		return d
+	}
+	return c.osRelease["usr/lib/os-release"]
+}
+
+// Packages returns the packages found so far, with package URLs set, sorted and without duplicates.
+func (c *Collector) Packages() []Package {
+	distro := c.Distro()
+	seen := map[string]struct{}{}
+	res := make([]Package, 0, len(c.packages))
+	for _, pkg := range c.packages {
+		if pkg.Name == "" {
+			continue
+		}
+		pkg.PURL = purl(pkg, distro)
+		if _, ok := seen[pkg.PURL]; ok {
+			continue
+		}
+		seen[pkg.PURL] = struct{}{}
+		res = append(res, pkg)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Type != res[j].Type {
+			return res[i].Type < res[j].Type
+		}
+		return res[i].PURL < res[j].PURL
+	})
+	return res
+}
+
+// parseOSRelease parses the os-release(5) format.
+func parseOSRelease(data []byte) Distro {
+	var d Distro
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			d.ID = value
+		case "VERSION_ID":
+			d.VersionID = value
+		case "PRETTY_NAME":
+			d.Name = value
+		}
+	}
+	return d
+}
+
+// isExecutable returns true if magic starts an ELF, Mach-O or PE file.
+func isExecutable(magic []byte) bool {
+	return bytes.HasPrefix(magic, []byte("\x7fELF")) ||
+		bytes.HasPrefix(magic, []byte("MZ")) ||
+		bytes.Equal(magic, []byte{0xcf, 0xfa, 0xed, 0xfe}) ||
+		bytes.Equal(magic, []byte{0xce, 0xfa, 0xed, 0xfe})
+}
+
+// purl returns the package URL (https://github.com/package-url/purl-spec) of pkg.
+func purl(pkg Package, distro Distro) string {
+	namespace := ""
+	qualifiers := url.Values{}
+	switch pkg.Type {
+	case TypeDeb, TypeRPM:
+		namespace = distro.ID
+		if distro.ID != "" && distro.VersionID != "" {
+			qualifiers.Set("distro", distro.ID+"-"+distro.VersionID)
+		}
+	case TypeApk:
+		namespace = "alpine"
+		if distro.ID != "" {
+			namespace = distro.ID
+		}
+	case TypePyPI:
+		// Names are case-insensitive and treat runs of -, _ and . alike.
+		pkg.Name = strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(pkg.Name))
+	}
+	if pkg.Arch != "" {
+		qualifiers.Set("arch", pkg.Arch)
+	}
+	if pkg.Epoch != "" && pkg.Epoch != "0" {
+		qualifiers.Set("epoch", pkg.Epoch)
+	}
+
+	name := pkg.Name
+	if pkg.Type == TypeGolang || pkg.Type == TypeNpm {
+		// Module paths and scoped npm packages carry their namespace in the name.
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			namespace, name = name[:i], name[i+1:]
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("pkg:" + pkg.Type + "/")
+	if namespace != "" {
+		for _, segment := range strings.Split(namespace, "/") {
+			b.WriteString(purlEscape(segment) + "/")
+		}
+	}
+	b.WriteString(purlEscape(name))
+	if pkg.Version != "" {
+		b.WriteString("@" + purlEscape(pkg.Version))
+	}
+	if len(qualifiers) != 0 {
+		b.WriteString("?" + qualifiers.Encode()) // Encode sorts by key, as the purl spec requires.
+	}
+	return b.String()
+}
+
+// purlEscape percent-encodes a package URL segment; unlike in URL paths, "@" and ":" are separators there.
+func purlEscape(s string) string {
+	return strings.NewReplacer("@", "%40", ":", "%3A").Replace(url.PathEscape(s))
+}
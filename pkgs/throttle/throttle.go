@@ -0,0 +1,161 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"math"
+
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/types"
+	"github.com/docker/go-units"
+	"golang.org/x/time/rate"
+
+	"gopull/pkgs/i18n"
+)
+
+// ParseRate parses a rate in bytes per second, with an optional binary unit suffix as used by curl --limit-rate,
+// e.g. "500k" or "5M".
+func ParseRate(s string) (int64, error) {
+	res, err := units.RAMInBytes(s)
+	if err != nil {
+		return 0, i18n.Errorf("invalid rate %q: %w", s, err)
+	}
+	if res <= 0 {
+		return 0, i18n.Errorf("invalid rate %q: must be positive", s)
+	}
+	return res, nil
+}
+
+// NewLimiter returns a token bucket allowing bytesPerSecond, with bursts of up to one second of transfer.
+// A limiter can be shared by any number of concurrent transfers, which are then limited in total.
+func NewLimiter(bytesPerSecond int64) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(min(bytesPerSecond, math.MaxInt32)))
+}
+
+// Limit returns the lowest rate of limiters in bytes per second, or 0 if there are none.
+func Limit(limiters []*rate.Limiter) int64 {
+	var res int64
+	for _, l := range limiters {
+		if l := int64(l.Limit()); res == 0 || l < res {
+			res = l
+		}
+	}
+	return res
+}
+
+// reader is an io.Reader reading no faster than all of limiters allow.
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*rate.Limiter
+}
+
+// NewReader returns an io.Reader reading from r no faster than all of limiters allow.
+// Read fails if ctx is canceled while waiting.
+func NewReader(ctx context.Context, r io.Reader, limiters []*rate.Limiter) io.Reader {
+	if len(limiters) == 0 {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, limiters: limiters}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	// A single wait can not be longer than the burst; read at most that much at once.
+	for _, l := range r.limiters {
+		if b := l.Burst(); len(p) > b {
+			p = p[:b]
+		}
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		for _, l := range r.limiters {
+			if waitErr := l.WaitN(r.ctx, n); waitErr != nil {
+				return n, waitErr
+			}
+		}
+	}
+	return n, err
+}
+
+// reference is an ImageReference whose blob reads and writes are limited.
+type reference struct {
+	types.ImageReference
+	limiters []*rate.Limiter
+}
+
+// WrapReference returns an ImageReference to ref, whose image sources read blobs and image destinations write blobs
+// no faster than all of limiters allow. Manifests and signatures are not limited.
+//
+// The sources and destinations only implement the public containers/image interfaces, so they don’t support
+// sigstore signatures, or reusing blobs from other repositories of a registry. Wrap local images, or registry
+// sources which already lost them, e.g. those of retry.WrapReference, which check signatures themselves.
+func WrapReference(ref types.ImageReference, limiters []*rate.Limiter) types.ImageReference {
+	if len(limiters) == 0 {
+		return ref
+	}
+	return &reference{ImageReference: ref, limiters: limiters}
+}
+
+func (ref *reference) NewImage(ctx context.Context, sys *types.SystemContext) (types.ImageCloser, error) {
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	img, err := image.FromSource(ctx, sys, src)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	return img, nil
+}
+
+func (ref *reference) NewImageSource(ctx context.Context, sys *types.SystemContext) (types.ImageSource, error) {
+	src, err := ref.ImageReference.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	return &imageSource{ImageSource: src, ref: ref}, nil
+}
+
+func (ref *reference) NewImageDestination(ctx context.Context, sys *types.SystemContext) (types.ImageDestination, error) {
+	dest, err := ref.ImageReference.NewImageDestination(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	return &imageDestination{ImageDestination: dest, ref: ref}, nil
+}
+
+type imageSource struct {
+	types.ImageSource
+	ref *reference
+}
+
+func (src *imageSource) Reference() types.ImageReference {
+	return src.ref
+}
+
+func (src *imageSource) GetBlob(ctx context.Context, info types.BlobInfo, cache types.BlobInfoCache) (io.ReadCloser, int64, error) {
+	rc, size, err := src.ImageSource.GetBlob(ctx, info, cache)
+	if err != nil {
+		return nil, 0, err
+	}
+	return readCloser{Reader: NewReader(ctx, rc, src.ref.limiters), Closer: rc}, size, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type imageDestination struct {
+	types.ImageDestination
+	ref *reference
+}
+
+func (dest *imageDestination) Reference() types.ImageReference {
+	return dest.ref
+}
+
+func (dest *imageDestination) PutBlob(ctx context.Context, stream io.Reader, inputInfo types.BlobInfo, cache types.BlobInfoCache, isConfig bool) (types.BlobInfo, error) {
+	return dest.ImageDestination.PutBlob(ctx, NewReader(ctx, stream, dest.ref.limiters), inputInfo, cache, isConfig)
+}
@@ -0,0 +1,93 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestParseRate(t *testing.T) {
+	for _, c := range []struct {
+		value    string
+		expected int64 // 0 if value is invalid
+	}{
+		{"1024", 1024},
+		{"500k", 500 << 10},
+		{"500K", 500 << 10},
+		{"5M", 5 << 20},
+		{"1.5m", 3 << 19},
+		{"2g", 2 << 30},
+		{"0", 0},
+		{"-1M", 0},
+		{"fast", 0},
+		{"", 0},
+	} {
+		res, err := ParseRate(c.value)
+		switch {
+		case c.expected == 0 && err == nil:
+			t.Errorf("%q: %d, expected an error", c.value, res)
+		case c.expected != 0 && (err != nil || res != c.expected):
+			t.Errorf("%q: %d, %v, expected %d", c.value, res, err, c.expected)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	if res := Limit(nil); res != 0 {
+		t.Errorf("no limiters: %d, expected 0", res)
+	}
+	if res := Limit([]*rate.Limiter{NewLimiter(5000), NewLimiter(2000), NewLimiter(3000)}); res != 2000 {
+		t.Errorf("%d, expected 2000", res)
+	}
+}
+
+func TestNewReaderShared(t *testing.T) {
+	// The limiter starts with a full bucket of one second of transfer: 4 readers of 1000 bytes at 1000 bytes/s
+	// in total need at least 3 more seconds. Without a limit on each, a global limit is shared.
+	const readers, size, bytesPerSecond = 4, 1000, 1000
+	limiter := NewLimiter(bytesPerSecond)
+	unlimited := NewLimiter(1 << 30)
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make([]error, readers)
+	for i := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := NewReader(context.Background(), bytes.NewReader(make([]byte, size)), []*rate.Limiter{unlimited, limiter})
+			n, err := io.Copy(io.Discard, r)
+			if err == nil && n != size {
+				err = io.ErrShortBuffer
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 2900*time.Millisecond || elapsed > 6*time.Second {
+		t.Errorf("reading %d bytes at %d bytes/s took %s, expected about 3s", readers*size, bytesPerSecond, elapsed)
+	}
+}
+
+func TestNewReaderCanceled(t *testing.T) {
+	limiter := NewLimiter(100)
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewReader(ctx, bytes.NewReader(make([]byte, 1000)), []*rate.Limiter{limiter})
+	buf := make([]byte, 1000)
+	// The first read consumes the burst.
+	if n, err := r.Read(buf); n != 100 || err != nil {
+		t.Fatalf("first read: %d, %v, expected 100 bytes", n, err)
+	}
+	cancel()
+	if _, err := r.Read(buf); !errors.Is(err, context.Canceled) {
+		t.Errorf("read after cancel: %v, expected %v", err, context.Canceled)
+	}
+}